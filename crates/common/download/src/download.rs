@@ -10,7 +10,9 @@ use log::warn;
 use nix::sys::statvfs;
 pub use partial_response::InvalidResponseError;
 use reqwest::header;
+use reqwest::Certificate;
 use reqwest::Identity;
+use reqwest::Proxy;
 use serde::Deserialize;
 use serde::Serialize;
 use std::fs;
@@ -107,6 +109,8 @@ pub struct Downloader {
     target_permission: PermissionEntry,
     backoff: ExponentialBackoff,
     identity: Option<Identity>,
+    proxy: Option<Proxy>,
+    root_certificate: Option<Certificate>,
 }
 
 impl Downloader {
@@ -118,6 +122,8 @@ impl Downloader {
             target_permission: PermissionEntry::default(),
             backoff: default_backoff(),
             identity,
+            proxy: None,
+            root_certificate: None,
         }
     }
 
@@ -133,6 +139,8 @@ impl Downloader {
             target_permission,
             backoff: default_backoff(),
             identity,
+            proxy: None,
+            root_certificate: None,
         }
     }
 
@@ -140,6 +148,16 @@ impl Downloader {
         self.backoff = backoff;
     }
 
+    /// Sends the requests through the given HTTP proxy
+    pub fn set_proxy(&mut self, proxy: Proxy) {
+        self.proxy = Some(proxy);
+    }
+
+    /// Trusts the given CA certificates, in addition to the system ones
+    pub fn set_root_certificate(&mut self, root_certificate: Certificate) {
+        self.root_certificate = Some(root_certificate);
+    }
+
     /// Downloads a file using an exponential backoff strategy.
     ///
     /// Partial backoff has a minimal interval of 30s and max elapsed time of
@@ -393,6 +411,12 @@ impl Downloader {
             if let Some(identity) = &self.identity {
                 client = client.identity(identity.clone());
             }
+            if let Some(proxy) = &self.proxy {
+                client = client.proxy(proxy.clone());
+            }
+            if let Some(root_certificate) = &self.root_certificate {
+                client = client.add_root_certificate(root_certificate.clone());
+            }
             let mut request = client.build()?.get(url.url());
             if let Some(Auth::Bearer(token)) = &url.auth {
                 request = request.bearer_auth(token)
@@ -557,6 +581,70 @@ mod tests {
         assert_eq!(file_content, "hello".as_bytes());
     }
 
+    #[test_case(None, None ; "external url without proxy credentials")]
+    #[test_case(Some("token"), None ; "tenant url without proxy credentials")]
+    #[test_case(None, Some(("user", "pass")) ; "external url with proxy credentials")]
+    #[test_case(Some("token"), Some(("user", "pass")) ; "tenant url with proxy credentials")]
+    #[tokio::test]
+    async fn downloader_download_through_proxy(
+        bearer_token: Option<&str>,
+        proxy_credentials: Option<(&str, &str)>,
+    ) {
+        let listener = TcpListener::bind("localhost:0").await.unwrap();
+        let port = listener.local_addr().unwrap().port();
+        let proxy_task = tokio::spawn(async move {
+            let (mut stream, _addr) = listener.accept().await.unwrap();
+            let (reader, mut writer) = stream.split();
+            let mut lines = BufReader::new(reader).lines();
+
+            // Read the request forwarded to the proxy, up to the empty line
+            let mut request = vec![];
+            while let Ok(Some(line)) = lines.next_line().await {
+                if line.is_empty() {
+                    break;
+                }
+                request.push(line.to_ascii_lowercase());
+            }
+
+            let msg = "HTTP/1.1 200 OK\r\ncontent-length: 5\r\nconnection: close\r\n\r\nhello";
+            writer.write_all(msg.as_bytes()).await.unwrap();
+            request
+        });
+
+        let target_dir_path = TempDir::new().unwrap();
+        let target_path = target_dir_path.path().join("test_download");
+
+        let mut url = DownloadInfo::new("http://tenant.example.com/some_file.txt");
+        if let Some(token) = bearer_token {
+            url = url.with_auth(Auth::new_bearer(token));
+        }
+
+        let mut proxy = Proxy::all(format!("http://localhost:{port}")).unwrap();
+        if let Some((username, password)) = proxy_credentials {
+            proxy = proxy.basic_auth(username, password);
+        }
+
+        let mut downloader = Downloader::new(target_path, None);
+        downloader.set_proxy(proxy);
+        downloader.download(&url).await.unwrap();
+
+        let file_content = std::fs::read(downloader.filename()).unwrap();
+        assert_eq!(file_content, "hello".as_bytes());
+
+        let request = proxy_task.await.unwrap();
+        assert_eq!(
+            request[0],
+            "get http://tenant.example.com/some_file.txt http/1.1"
+        );
+
+        let has_header = |name: &str| request.iter().any(|line| line.starts_with(name));
+        assert_eq!(has_header("authorization: bearer"), bearer_token.is_some());
+        assert_eq!(
+            has_header("proxy-authorization: basic"),
+            proxy_credentials.is_some()
+        );
+    }
+
     #[cfg(target_os = "linux")]
     #[tokio::test]
     #[ignore = "Overriding Content-Length doesn't work in mockito"]
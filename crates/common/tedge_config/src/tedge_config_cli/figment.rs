@@ -73,6 +73,19 @@ pub fn extract_data<T: DeserializeOwned, Sources: ConfigSources>(
     }
 }
 
+/// Find where the value of the given configuration key is set
+///
+/// Returns `None` when the key is set neither in the TOML file nor by a `TEDGE_` prefixed
+/// environment variable, i.e. when its value, if any, is a built-in default.
+pub fn find_source(path: impl AsRef<Path>, key: &str) -> Option<ConfigurationSource> {
+    let env = TEdgeEnv::default();
+    let figment = Figment::new().merge(Toml::file(path)).merge(env.provider());
+
+    figment
+        .find_metadata(key)
+        .and_then(|metadata| ConfigurationSource::infer(&env, key, metadata))
+}
+
 #[cfg(feature = "test")]
 pub fn extract_from_toml_str<T: DeserializeOwned>(toml: &str) -> Result<T, TEdgeConfigError> {
     let env = TEdgeEnv::default();
@@ -151,7 +164,8 @@ fn add_error_context(mut error: figment::Error, env: &TEdgeEnv) -> TEdgeConfigEr
     TEdgeConfigError::Figment(error)
 }
 
-enum ConfigurationSource {
+#[derive(Debug, PartialEq, Eq)]
+pub enum ConfigurationSource {
     TomlFile(PathBuf),
     EnvVariable(String),
     Unknown(String),
@@ -275,6 +289,37 @@ mod tests {
         })
     }
 
+    #[test]
+    fn finds_the_source_of_a_configuration_value() {
+        figment::Jail::expect_with(|jail| {
+            jail.create_file(
+                "tedge.toml",
+                r#"
+            [c8y]
+            url = "test.c8y.io"
+
+            [mqtt.bridge]
+            built_in = false
+            "#,
+            )?;
+
+            jail.set_env("TEDGE_MQTT_BRIDGE_BUILT_IN", "true");
+
+            assert!(matches!(
+                find_source("tedge.toml", "c8y.url"),
+                Some(ConfigurationSource::TomlFile(path)) if path.ends_with("tedge.toml")
+            ));
+            assert_eq!(
+                find_source("tedge.toml", "mqtt.bridge.built_in"),
+                Some(ConfigurationSource::EnvVariable(
+                    "TEDGE_MQTT_BRIDGE_BUILT_IN".into()
+                ))
+            );
+            assert_eq!(find_source("tedge.toml", "mqtt.bind.port"), None);
+            Ok(())
+        })
+    }
+
     #[test]
     fn specifies_file_name_and_variable_path_in_relevant_warnings() {
         #[derive(Deserialize)]
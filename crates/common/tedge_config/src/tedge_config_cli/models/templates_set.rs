@@ -2,9 +2,14 @@ use std::convert::Infallible;
 use std::convert::TryInto;
 use std::str::FromStr;
 
-/// Represents a set of smartrest templates.
+/// Represents a list of strings, such as smartrest templates, MQTT topics or host names.
 ///
-/// New type to add conversion methods and deduplicate provided templates.
+/// Despite its name, this type is used by all the list settings, and not only `c8y.smartrest.templates`.
+/// New type to add conversion methods and deduplicate the values appended with `tedge config add`.
+///
+/// From the command line or an environment variable, the values can be given
+/// either comma-delimited (`a.com,b.com`) or as a JSON array (`["a.com","b.com"]`).
+/// A value starting with `[` is always parsed as a JSON array.
 #[derive(Clone, Debug, Default, serde::Serialize, serde::Deserialize, Eq, PartialEq)]
 #[serde(from = "FromTomlOrCli")]
 pub struct TemplatesSet(pub Vec<String>);
@@ -15,8 +20,15 @@ impl doku::Document for TemplatesSet {
     }
 }
 
+#[derive(thiserror::Error, Debug)]
+#[error("Failed to parse '{input}' as a JSON array of strings: {error}")]
+pub struct InvalidTemplatesSet {
+    input: String,
+    error: serde_json::Error,
+}
+
 #[derive(serde::Deserialize)]
-#[serde(from = "String")]
+#[serde(try_from = "String")]
 struct CommaDelimited(Vec<String>);
 
 #[derive(serde::Deserialize)]
@@ -26,15 +38,34 @@ enum FromTomlOrCli {
     Cli(CommaDelimited),
 }
 
-impl From<String> for CommaDelimited {
-    fn from(value: String) -> Self {
-        Self(
+/// Parse the given value as a JSON array of strings, if it looks like one
+fn json_array(value: &str) -> Option<Result<Vec<String>, InvalidTemplatesSet>> {
+    if !value.trim_start().starts_with('[') {
+        return None;
+    }
+    Some(
+        serde_json::from_str(value).map_err(|error| InvalidTemplatesSet {
+            input: value.to_string(),
+            error,
+        }),
+    )
+}
+
+impl TryFrom<String> for CommaDelimited {
+    type Error = InvalidTemplatesSet;
+
+    fn try_from(value: String) -> Result<Self, Self::Error> {
+        if let Some(entries) = json_array(&value) {
+            return Ok(Self(entries?));
+        }
+
+        Ok(Self(
             value
                 .split(',')
                 .map(|s| s.trim().to_owned())
                 .filter(|s| !s.is_empty())
                 .collect(),
-        )
+        ))
     }
 }
 
@@ -79,23 +110,31 @@ impl From<TemplatesSet> for String {
     }
 }
 
-impl From<String> for TemplatesSet {
-    fn from(val: String) -> Self {
-        Self::from(val.as_str())
+impl TryFrom<String> for TemplatesSet {
+    type Error = InvalidTemplatesSet;
+
+    fn try_from(val: String) -> Result<Self, Self::Error> {
+        Self::try_from(val.as_str())
     }
 }
 
-impl<'a> From<&'a str> for TemplatesSet {
-    fn from(val: &'a str) -> Self {
+impl<'a> TryFrom<&'a str> for TemplatesSet {
+    type Error = InvalidTemplatesSet;
+
+    fn try_from(val: &'a str) -> Result<Self, Self::Error> {
+        if let Some(strings) = json_array(val) {
+            return Ok(TemplatesSet(strings?));
+        }
+
         let strings = val.split(',').map(|ss| ss.into()).collect();
-        TemplatesSet(strings)
+        Ok(TemplatesSet(strings))
     }
 }
 
 impl FromStr for TemplatesSet {
-    type Err = Infallible;
+    type Err = InvalidTemplatesSet;
     fn from_str(value: &str) -> Result<Self, Self::Err> {
-        Ok(Self::from(value))
+        Self::try_from(value)
     }
 }
 
@@ -104,3 +143,43 @@ impl std::fmt::Display for TemplatesSet {
         write!(f, "{:?}", self.0)
     }
 }
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use test_case::test_case;
+
+    #[test_case("a.com,b.com" ; "comma delimited")]
+    #[test_case(r#"["a.com","b.com"]"# ; "json array")]
+    #[test_case(r#" [ "a.com", "b.com" ] "# ; "json array with spaces")]
+    fn parse_list_from_cli(value: &str) {
+        assert_eq!(
+            value.parse::<TemplatesSet>().unwrap(),
+            TemplatesSet(vec!["a.com".into(), "b.com".into()])
+        );
+    }
+
+    #[test_case("a.com,b.com" ; "comma delimited")]
+    #[test_case(r#"["a.com","b.com"]"# ; "json array")]
+    fn parse_list_from_environment(value: &str) {
+        let TemplatesSet(entries) =
+            FromTomlOrCli::Cli(CommaDelimited::try_from(value.to_owned()).unwrap()).into();
+        assert_eq!(entries, vec!["a.com", "b.com"]);
+    }
+
+    #[test_case(r#"["a.com", b.com]"# ; "unquoted entry")]
+    #[test_case("[1,2]" ; "not strings")]
+    #[test_case(r#"["a.com","b.com""# ; "unterminated array")]
+    fn reject_malformed_json_arrays(value: &str) {
+        assert!(value.parse::<TemplatesSet>().is_err());
+        assert!(CommaDelimited::try_from(value.to_owned()).is_err());
+    }
+
+    #[test]
+    fn displayed_list_can_be_parsed_back() {
+        let set = TemplatesSet(vec!["a.com".into(), "b.com".into()]);
+
+        assert_eq!(set.to_string(), r#"["a.com", "b.com"]"#);
+        assert_eq!(set.to_string().parse::<TemplatesSet>().unwrap(), set);
+    }
+}
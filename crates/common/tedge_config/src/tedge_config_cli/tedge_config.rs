@@ -115,6 +115,70 @@ impl TEdgeConfig {
         }
         client_auth
     }
+
+    /// The HTTP proxy to be used to download files, if any
+    ///
+    /// Requests to the local host, to the File Transfer Service host
+    /// and to the local Cumulocity HTTP proxy host are not proxied.
+    pub fn download_proxy(&self) -> anyhow::Result<Option<reqwest::Proxy>> {
+        use ReadableKey::*;
+
+        let Some(address) = self.http.proxy.address.or_none() else {
+            return Ok(None);
+        };
+        let proxy_url = url::Url::parse(address).with_context(|| {
+            format!("parsing the proxy address (from {HttpProxyAddress}): {address}")
+        })?;
+
+        let local_hosts = [
+            self.http.client.host.clone(),
+            self.c8y.proxy.client.host.clone(),
+        ];
+        let mut proxy = reqwest::Proxy::custom(move |url| {
+            is_proxied(url, &local_hosts).then(|| proxy_url.clone())
+        });
+
+        if let Some(username) = self.http.proxy.username.or_none() {
+            let password = self
+                .http
+                .proxy
+                .password
+                .or_none()
+                .map_or("", |p| p.as_str());
+            proxy = proxy.basic_auth(username, password);
+        }
+
+        Ok(Some(proxy))
+    }
+
+    /// The CA certificates to be trusted, in addition to the system ones, to download files
+    pub fn download_root_certificate(&self) -> anyhow::Result<Option<reqwest::Certificate>> {
+        use ReadableKey::*;
+
+        let Some(ca_path) = self.http.proxy.ca_path.or_none() else {
+            return Ok(None);
+        };
+        let pem = std::fs::read(ca_path).with_context(|| {
+            format!("reading CA certificates (from {HttpProxyCaPath}): {ca_path}")
+        })?;
+        let certificate = reqwest::Certificate::from_pem(&pem).with_context(|| {
+            format!("parsing CA certificates (from {HttpProxyCaPath}): {ca_path}")
+        })?;
+
+        Ok(Some(certificate))
+    }
+}
+
+/// Tell if a request to the given URL has to be sent to the HTTP proxy, i.e. is not for a local host
+fn is_proxied(url: &url::Url, local_hosts: &[Arc<str>]) -> bool {
+    let is_local_host = |host: &str| local_hosts.iter().any(|local| &**local == host);
+    let is_local = match url.host() {
+        Some(url::Host::Domain(domain)) => domain == "localhost" || is_local_host(domain),
+        Some(url::Host::Ipv4(ip)) => ip.is_loopback() || is_local_host(&ip.to_string()),
+        Some(url::Host::Ipv6(ip)) => ip.is_loopback(),
+        None => true,
+    };
+    !is_local
 }
 
 #[derive(serde::Deserialize, serde::Serialize, Clone, Copy, PartialEq, Eq, Debug)]
@@ -499,6 +563,13 @@ define_tedge_config! {
             firmware_update: bool,
         },
 
+        config: {
+            /// Compress configuration snapshots with gzip before uploading them to Cumulocity
+            #[tedge_config(note = "Independently of this setting, gzipped configuration updates are decompressed before being deployed, unless the target file is itself a .gz or .tgz archive.")]
+            #[tedge_config(example = "true", default(value = false))]
+            compress: bool,
+        },
+
         proxy: {
             bind: {
                 /// The IP address local Cumulocity HTTP proxy binds to
@@ -537,6 +608,12 @@ define_tedge_config! {
             #[tedge_config(example = "/etc/ssl/certs")]
             #[doku(as = "PathBuf")]
             ca_path: Utf8PathBuf,
+
+            /// Set of host names, besides the `c8y.http` host, that serve the Cumulocity tenant.
+            /// Downloads from these hosts are authenticated with the device credentials.
+            #[tedge_config(note = "If empty, any host sharing the parent domain of `c8y.http` is considered part of the tenant.")]
+            #[tedge_config(example = "t12345.cumulocity.com,dashboard.example.com", default(function = "TemplatesSet::default"))]
+            trusted_hosts: TemplatesSet,
         },
 
         bridge: {
@@ -814,6 +891,27 @@ define_tedge_config! {
         #[tedge_config(example = "/etc/ssl/certs")]
         #[doku(as = "PathBuf")]
         ca_path: Utf8PathBuf,
+
+        proxy: {
+            /// The address of the HTTP proxy used by tedge-agent and the Cumulocity mapper to reach external services
+            #[tedge_config(note = "Requests to the local host, to `http.client.host` and to `c8y.proxy.client.host` are never sent to the proxy.")]
+            #[tedge_config(example = "http://proxy.example.com:8080")]
+            address: String,
+
+            /// The username used to authenticate to the HTTP proxy
+            #[tedge_config(example = "tedge")]
+            username: String,
+
+            /// The password used to authenticate to the HTTP proxy
+            #[tedge_config(note = "The password is not displayed by `tedge config list`.")]
+            password: String,
+
+            /// Path to a file containing the PEM encoded CA certificates that are trusted,
+            /// in addition to the system ones, when tedge-agent and the Cumulocity mapper connect to external services
+            #[tedge_config(example = "/etc/tedge/device-certs/proxy-ca.pem")]
+            #[doku(as = "PathBuf")]
+            ca_path: Utf8PathBuf,
+        },
     },
 
     agent: {
@@ -1266,6 +1364,49 @@ mod tests {
         key.parse::<ReadableKey>().unwrap();
     }
 
+    #[test]
+    fn no_download_proxy_by_default() {
+        let config =
+            TEdgeConfig::from_dto(&TEdgeConfigDto::default(), &TEdgeConfigLocation::default());
+
+        assert!(config.download_proxy().unwrap().is_none());
+        assert!(config.download_root_certificate().unwrap().is_none());
+    }
+
+    #[test]
+    fn invalid_download_proxy_address_is_rejected() {
+        let mut dto = TEdgeConfigDto::default();
+        dto.http.proxy.address = Some("not a url".into());
+        let config = TEdgeConfig::from_dto(&dto, &TEdgeConfigLocation::default());
+
+        let err = config.download_proxy().unwrap_err();
+        assert!(err.to_string().contains("http.proxy.address"));
+    }
+
+    #[test_case::test_case("https://tenant.cumulocity.com/inventory/binaries/1234", true ; "tenant url")]
+    #[test_case::test_case("http://example.com/config.toml", true ; "external url")]
+    #[test_case::test_case("http://localhost:8000/te/v1/files/config", false ; "localhost")]
+    #[test_case::test_case("http://127.0.0.1:8001/c8y/inventory/binaries/1234", false ; "loopback ipv4")]
+    #[test_case::test_case("http://[::1]:8000/te/v1/files/config", false ; "loopback ipv6")]
+    #[test_case::test_case("http://tedge-hostname:8000/te/v1/files/config", false ; "file transfer service host")]
+    #[test_case::test_case("http://192.168.1.2:8001/c8y/inventory/binaries/1234", false ; "cumulocity http proxy host")]
+    fn only_requests_to_remote_hosts_are_proxied(url: &str, expected: bool) {
+        let local_hosts: [Arc<str>; 2] = ["tedge-hostname".into(), "192.168.1.2".into()];
+        let url = url::Url::parse(url).unwrap();
+
+        assert_eq!(is_proxied(&url, &local_hosts), expected);
+    }
+
+    #[test]
+    fn missing_download_ca_file_is_reported() {
+        let mut dto = TEdgeConfigDto::default();
+        dto.http.proxy.ca_path = Some("/non/existent/ca.pem".into());
+        let config = TEdgeConfig::from_dto(&dto, &TEdgeConfigLocation::default());
+
+        let err = config.download_root_certificate().unwrap_err();
+        assert!(err.to_string().contains("http.proxy.ca_path"));
+    }
+
     #[test]
     fn missing_c8y_http_directs_user_towards_setting_c8y_url() {
         let dto = TEdgeConfigDto::default();
@@ -6,6 +6,7 @@ use crate::tedge_config_cli::figment::FileAndEnvironment;
 use crate::tedge_config_cli::figment::FileOnly;
 use crate::tedge_config_cli::figment::UnusedValueWarnings;
 use crate::ConfigSettingResult;
+use crate::ReadableKey;
 use crate::TEdgeConfig;
 use crate::TEdgeConfigDto;
 use crate::TEdgeConfigError;
@@ -16,6 +17,8 @@ use serde::Serialize;
 use tedge_utils::fs::atomically_write_file_sync;
 use tracing::debug;
 
+pub use crate::tedge_config_cli::figment::ConfigurationSource;
+
 pub const DEFAULT_TEDGE_CONFIG_PATH: &str = "/etc/tedge";
 const TEDGE_CONFIG_FILE: &str = "tedge.toml";
 /// Information about where `tedge.toml` is located.
@@ -85,6 +88,18 @@ impl TEdgeConfigLocation {
         Ok(TEdgeConfig::from_dto(&dto, self))
     }
 
+    /// Load a configuration made only of the built-in default values
+    pub fn load_defaults(&self) -> TEdgeConfig {
+        TEdgeConfig::from_dto(&TEdgeConfigDto::default(), self)
+    }
+
+    /// Find where the value of the given key is set
+    ///
+    /// Returns `None` if the value is set neither in `tedge.toml` nor by an environment variable.
+    pub fn find_source(&self, key: ReadableKey) -> Option<ConfigurationSource> {
+        super::figment::find_source(self.toml_path(), key.as_str())
+    }
+
     fn load_dto<Sources: ConfigSources>(
         &self,
         path: &Utf8Path,
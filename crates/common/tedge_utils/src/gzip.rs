@@ -0,0 +1,89 @@
+use flate2::read::GzDecoder;
+use flate2::write::GzEncoder;
+use flate2::Compression;
+use std::fs::File;
+use std::io;
+use std::io::BufReader;
+use std::io::Read;
+use std::path::Path;
+
+/// The two bytes every gzip stream starts with
+const GZIP_MAGIC_NUMBER: [u8; 2] = [0x1f, 0x8b];
+
+/// Compress the content of the `src` file into the `dest` file
+pub fn compress_file(src: impl AsRef<Path>, dest: impl AsRef<Path>) -> io::Result<()> {
+    let mut input = BufReader::new(File::open(src)?);
+    let output = File::create(dest)?;
+
+    let mut encoder = GzEncoder::new(output, Compression::default());
+    io::copy(&mut input, &mut encoder)?;
+    encoder.finish()?.sync_all()?;
+
+    Ok(())
+}
+
+/// Decompress the content of the gzipped `src` file into the `dest` file
+pub fn decompress_file(src: impl AsRef<Path>, dest: impl AsRef<Path>) -> io::Result<()> {
+    let mut decoder = GzDecoder::new(BufReader::new(File::open(src)?));
+    let mut output = File::create(dest)?;
+
+    io::copy(&mut decoder, &mut output)?;
+    output.sync_all()?;
+
+    Ok(())
+}
+
+/// Return true if the given file content starts with the gzip magic number
+pub fn is_gzipped(path: impl AsRef<Path>) -> io::Result<bool> {
+    let mut header = [0u8; 2];
+    match File::open(path)?.read_exact(&mut header) {
+        Ok(()) => Ok(header == GZIP_MAGIC_NUMBER),
+        Err(err) if err.kind() == io::ErrorKind::UnexpectedEof => Ok(false),
+        Err(err) => Err(err),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tempfile::TempDir;
+
+    #[test]
+    fn compressed_file_can_be_decompressed() {
+        let temp_dir = TempDir::new().unwrap();
+        let original = temp_dir.path().join("tedge.toml");
+        let compressed = temp_dir.path().join("tedge.toml.gz");
+        let decompressed = temp_dir.path().join("tedge.toml.copy");
+        let content = "[c8y]\nurl = \"example.cumulocity.com\"\n".repeat(100);
+        std::fs::write(&original, &content).unwrap();
+
+        compress_file(&original, &compressed).unwrap();
+        assert!(is_gzipped(&compressed).unwrap());
+        assert!(std::fs::metadata(&compressed).unwrap().len() < content.len() as u64);
+
+        decompress_file(&compressed, &decompressed).unwrap();
+        assert_eq!(std::fs::read_to_string(&decompressed).unwrap(), content);
+    }
+
+    #[test]
+    fn plain_files_are_not_gzipped() {
+        let temp_dir = TempDir::new().unwrap();
+        let plain = temp_dir.path().join("plain.txt");
+        let empty = temp_dir.path().join("empty.txt");
+        std::fs::write(&plain, "some configuration").unwrap();
+        std::fs::write(&empty, "").unwrap();
+
+        assert!(!is_gzipped(&plain).unwrap());
+        assert!(!is_gzipped(&empty).unwrap());
+    }
+
+    #[test]
+    fn decompressing_a_plain_file_fails() {
+        let temp_dir = TempDir::new().unwrap();
+        let plain = temp_dir.path().join("plain.txt");
+        let dest = temp_dir.path().join("dest.txt");
+        std::fs::write(&plain, "some configuration").unwrap();
+
+        assert!(decompress_file(&plain, &dest).is_err());
+    }
+}
@@ -9,5 +9,8 @@ pub mod futures;
 #[cfg(feature = "fs-notify")]
 pub mod notify;
 
+#[cfg(feature = "gzip")]
+pub mod gzip;
+
 #[cfg(feature = "timestamp")]
 pub mod timestamp;
@@ -27,6 +27,7 @@ pub struct C8yEndPoint {
     pub device_id: String,
     pub token: Option<String>,
     devices_internal_id: HashMap<String, String>,
+    trusted_hosts: Vec<String>,
 }
 
 impl C8yEndPoint {
@@ -36,9 +37,19 @@ impl C8yEndPoint {
             device_id: device_id.into(),
             token: None,
             devices_internal_id: HashMap::new(),
+            trusted_hosts: Vec::new(),
         }
     }
 
+    /// Set the host names, in addition to the configured c8y host, that are known to serve the tenant
+    ///
+    /// When this list is not empty, only URLs pointing to one of these hosts
+    /// or to the configured c8y host are considered as tenant URLs.
+    pub fn with_trusted_hosts(mut self, trusted_hosts: Vec<String>) -> Self {
+        self.trusted_hosts = trusted_hosts;
+        self
+    }
+
     pub fn get_internal_id(&self, device_id: String) -> Result<String, C8yEndPointError> {
         match self.devices_internal_id.get(&device_id) {
             Some(internal_id) => Ok(internal_id.to_string()),
@@ -96,11 +107,6 @@ impl C8yEndPoint {
     }
 
     pub fn maybe_tenant_url(&self, url: &str) -> Option<Url> {
-        // c8y URL may contain either `Tenant Name` or Tenant Id` so they can be one of following options:
-        // * <tenant_name>.<domain> eg: sample.c8y.io
-        // * <tenant_id>.<domain> eg: t12345.c8y.io
-        // These URLs may be both equivalent and point to the same tenant.
-        // We are going to remove that and only check if the domain is the same.
         let (tenant_host, _port) = self
             .c8y_host
             .split_once(':')
@@ -108,6 +114,20 @@ impl C8yEndPoint {
         let url = Url::parse(url).ok()?;
         let url_host = url.domain()?;
 
+        if !self.trusted_hosts.is_empty() {
+            // The trusted hosts are explicitly given: no need to guess.
+            let is_trusted = std::iter::once(tenant_host)
+                .chain(self.trusted_hosts.iter().map(|host| host.as_str()))
+                .map(|host| host.split_once(':').map_or(host, |(host, _port)| host))
+                .any(|host| host.eq_ignore_ascii_case(url_host));
+            return is_trusted.then_some(url);
+        }
+
+        // c8y URL may contain either `Tenant Name` or Tenant Id` so they can be one of following options:
+        // * <tenant_name>.<domain> eg: sample.c8y.io
+        // * <tenant_id>.<domain> eg: t12345.c8y.io
+        // These URLs may be both equivalent and point to the same tenant.
+        // We are going to remove that and only check if the domain is the same.
         let (_, host) = url_host.split_once('.').unwrap_or((url_host, ""));
         let (_, c8y_host) = tenant_host.split_once('.').unwrap_or((tenant_host, ""));
 
@@ -269,6 +289,24 @@ mod tests {
         assert!(c8y.maybe_tenant_url("http://xyz.com").is_none());
     }
 
+    #[test_case("https://dashboard.hennypenny.com/path/to/file")]
+    #[test_case("https://t517788845.us.cumulocity.com/path/to/file")]
+    #[test_case("https://T517788845.us.cumulocity.com:443/path")]
+    fn url_is_my_tenant_with_trusted_hosts(url: &str) {
+        let c8y = C8yEndPoint::new("dashboard.hennypenny.com", "test_device")
+            .with_trusted_hosts(vec!["t517788845.us.cumulocity.com".into()]);
+        assert_eq!(c8y.maybe_tenant_url(url), Some(url.parse().unwrap()));
+    }
+
+    #[test_case("https://xyz.com/path/to/file")]
+    #[test_case("https://other.hennypenny.com/path/to/file")]
+    #[test_case("https://t12345.us.cumulocity.com/path/to/file")]
+    fn url_is_not_my_tenant_with_trusted_hosts(url: &str) {
+        let c8y = C8yEndPoint::new("dashboard.hennypenny.com:443", "test_device")
+            .with_trusted_hosts(vec!["t517788845.us.cumulocity.com".into()]);
+        assert!(c8y.maybe_tenant_url(url).is_none());
+    }
+
     #[test]
     fn check_non_cached_internal_id_for_a_device() {
         let mut c8y = C8yEndPoint::new("test_host", "test_device");
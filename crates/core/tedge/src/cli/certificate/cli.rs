@@ -35,7 +35,13 @@ pub enum TEdgeCertCli {
     },
 
     /// Renew the device certificate
-    Renew,
+    ///
+    /// The renewed certificate keeps the device identifier of the current certificate.
+    Renew {
+        /// Change the device identifier used as the common name for the renewed certificate
+        #[clap(long = "new-device-id")]
+        new_id: Option<String>,
+    },
 
     /// Show the device certificate, if any
     Show,
@@ -108,11 +114,12 @@ impl BuildCommand for TEdgeCertCli {
                 };
                 cmd.into_boxed()
             }
-            TEdgeCertCli::Renew => {
+            TEdgeCertCli::Renew { new_id } => {
                 let cmd = RenewCertCmd {
                     cert_path: config.device.cert_path.clone(),
                     key_path: config.device.key_path.clone(),
                     bridge_location,
+                    new_id,
                 };
                 cmd.into_boxed()
             }
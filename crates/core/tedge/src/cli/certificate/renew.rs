@@ -10,6 +10,9 @@ pub struct RenewCertCmd {
     pub cert_path: Utf8PathBuf,
     pub key_path: Utf8PathBuf,
     pub bridge_location: BridgeLocation,
+
+    /// The device identifier to be used instead of the common name of the current certificate
+    pub new_id: Option<String>,
 }
 
 impl Command for RenewCertCmd {
@@ -27,9 +30,19 @@ impl Command for RenewCertCmd {
 
 impl RenewCertCmd {
     fn renew_test_certificate(&self, config: &NewCertificateConfig) -> Result<(), CertError> {
-        let id = cn_of_self_signed_certificate(&self.cert_path)?;
+        // The device identity is preserved, unless explicitly changed
+        let current_id = cn_of_self_signed_certificate(&self.cert_path)?;
+        let id = match &self.new_id {
+            Some(new_id) if *new_id != current_id => {
+                eprintln!("Changing the device id from '{current_id}' to '{new_id}'");
+                new_id.clone()
+            }
+            _ => current_id,
+        };
 
-        // Remove only certificate
+        // Remove only certificate, keeping its content to restore it on failure
+        let current_cert = std::fs::read(&self.cert_path)
+            .map_err(|e| CertError::IoError(e).cert_context(self.cert_path.clone()))?;
         std::fs::remove_file(&self.cert_path)
             .map_err(|e| CertError::IoError(e).cert_context(self.cert_path.clone()))?;
 
@@ -42,7 +55,12 @@ impl RenewCertCmd {
             bridge_location: self.bridge_location,
         };
 
-        create_cmd.renew_test_certificate(config)
+        create_cmd.renew_test_certificate(config).map_err(|err| {
+            if let Err(e) = std::fs::write(&self.cert_path, current_cert) {
+                eprintln!("Failed to restore the previous certificate: {e}");
+            }
+            err
+        })
     }
 }
 
@@ -85,6 +103,7 @@ mod tests {
             cert_path: cert_path.clone(),
             key_path: key_path.clone(),
             bridge_location: BridgeLocation::Mosquitto,
+            new_id: None,
         };
         cmd.renew_test_certificate(&NewCertificateConfig::default())
             .unwrap();
@@ -110,6 +129,61 @@ mod tests {
         );
     }
 
+    #[test]
+    fn renew_certificate_for_a_new_device_id() {
+        let dir = tempdir().unwrap();
+        let cert_path = temp_file_path(&dir, "my-device-cert.pem");
+        let key_path = temp_file_path(&dir, "my-device-key.pem");
+        create_test_certificate("my-device-id", &cert_path, &key_path);
+
+        let cmd = RenewCertCmd {
+            cert_path: cert_path.clone(),
+            key_path: key_path.clone(),
+            bridge_location: BridgeLocation::Mosquitto,
+            new_id: Some("my-new-device-id".to_string()),
+        };
+        cmd.renew_test_certificate(&NewCertificateConfig::default())
+            .unwrap();
+
+        assert_eq!(
+            cn_of_self_signed_certificate(&cert_path).unwrap(),
+            "my-new-device-id"
+        );
+    }
+
+    #[test]
+    fn failed_renewal_keeps_the_current_certificate() {
+        let dir = tempdir().unwrap();
+        let cert_path = temp_file_path(&dir, "my-device-cert.pem");
+        let key_path = temp_file_path(&dir, "my-device-key.pem");
+        create_test_certificate("my-device-id", &cert_path, &key_path);
+        let first_cert = std::fs::read_to_string(&cert_path).unwrap();
+
+        let cmd = RenewCertCmd {
+            cert_path: cert_path.clone(),
+            key_path: key_path.clone(),
+            bridge_location: BridgeLocation::Mosquitto,
+            new_id: Some("an+invalid/device#id".to_string()),
+        };
+        assert!(cmd
+            .renew_test_certificate(&NewCertificateConfig::default())
+            .is_err());
+
+        assert_eq!(std::fs::read_to_string(&cert_path).unwrap(), first_cert);
+    }
+
+    fn create_test_certificate(id: &str, cert_path: &Utf8PathBuf, key_path: &Utf8PathBuf) {
+        let cmd = CreateCertCmd {
+            id: String::from(id),
+            cert_path: cert_path.clone(),
+            key_path: key_path.clone(),
+            csr_path: None,
+            bridge_location: BridgeLocation::Mosquitto,
+        };
+        cmd.create_test_certificate(&NewCertificateConfig::default())
+            .unwrap();
+    }
+
     fn temp_file_path(dir: &TempDir, filename: &str) -> Utf8PathBuf {
         dir.path().join(filename).try_into().unwrap()
     }
@@ -10,6 +10,14 @@ pub enum ConfigCmd {
     Get {
         /// Configuration key. Run `tedge config list --doc` for available keys
         key: ReadableKey,
+
+        /// Prints the built-in default value, ignoring any value set in tedge.toml or the environment
+        #[clap(long = "default", conflicts_with = "show_source")]
+        show_default: bool,
+
+        /// Prints where the value comes from: the built-in defaults, tedge.toml or an environment variable
+        #[clap(long = "source")]
+        show_source: bool,
     },
 
     /// Set or update the provided configuration key with the given value
@@ -62,9 +70,30 @@ impl BuildCommand for ConfigCmd {
         let config_location = context.config_location;
 
         match self {
-            ConfigCmd::Get { key } => Ok(GetConfigCommand {
+            ConfigCmd::Get {
+                key,
+                show_default: true,
+                ..
+            } => Ok(GetConfigCommand {
+                key,
+                config: config_location.load_defaults(),
+                is_default: true,
+            }
+            .into_boxed()),
+            ConfigCmd::Get {
+                key,
+                show_source: true,
+                ..
+            } => Ok(GetConfigSourceCommand {
+                key,
+                config: config_location.load()?,
+                config_location,
+            }
+            .into_boxed()),
+            ConfigCmd::Get { key, .. } => Ok(GetConfigCommand {
                 key,
                 config: config_location.load()?,
+                is_default: false,
             }
             .into_boxed()),
             ConfigCmd::Set { key, value } => Ok(SetConfigCommand {
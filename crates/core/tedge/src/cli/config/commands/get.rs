@@ -1,15 +1,24 @@
 use tedge_config::ReadableKey;
+use tedge_config::TEdgeConfigLocation;
 
 use crate::command::Command;
 
 pub struct GetConfigCommand {
     pub key: ReadableKey,
     pub config: tedge_config::TEdgeConfig,
+    pub is_default: bool,
 }
 
 impl Command for GetConfigCommand {
     fn description(&self) -> String {
-        format!("get the configuration value for key: '{}'", self.key)
+        if self.is_default {
+            format!(
+                "get the default configuration value for key: '{}'",
+                self.key
+            )
+        } else {
+            format!("get the configuration value for key: '{}'", self.key)
+        }
     }
 
     fn execute(&self) -> anyhow::Result<()> {
@@ -17,6 +26,49 @@ impl Command for GetConfigCommand {
             Ok(value) => {
                 println!("{}", value);
             }
+            Err(tedge_config::ReadError::ConfigNotSet { .. }) if self.is_default => {
+                eprintln!(
+                    "The provided config key: '{}' has no default value",
+                    self.key
+                );
+            }
+            Err(tedge_config::ReadError::ConfigNotSet { .. }) => {
+                eprintln!("The provided config key: '{}' is not set", self.key);
+            }
+            Err(tedge_config::ReadError::ReadOnlyNotFound { message, key }) => {
+                eprintln!("The provided config key: '{key}' is not configured: {message}",);
+            }
+            Err(err) => return Err(err.into()),
+        }
+
+        Ok(())
+    }
+}
+
+pub struct GetConfigSourceCommand {
+    pub key: ReadableKey,
+    pub config: tedge_config::TEdgeConfig,
+    pub config_location: TEdgeConfigLocation,
+}
+
+impl Command for GetConfigSourceCommand {
+    fn description(&self) -> String {
+        format!(
+            "get the source of the configuration value for key: '{}'",
+            self.key
+        )
+    }
+
+    fn execute(&self) -> anyhow::Result<()> {
+        if let Some(source) = self.config_location.find_source(self.key) {
+            println!("{source}");
+            return Ok(());
+        }
+
+        match self.config.read_string(self.key) {
+            Ok(_) => {
+                println!("default");
+            }
             Err(tedge_config::ReadError::ConfigNotSet { .. }) => {
                 eprintln!("The provided config key: '{}' is not set", self.key);
             }
@@ -7,6 +7,9 @@ use tedge_config::ReadableKey;
 use tedge_config::TEdgeConfig;
 use tedge_config::READABLE_KEYS;
 
+/// The keys whose values are never displayed
+const SECRET_KEYS: &[ReadableKey] = &[ReadableKey::HttpProxyPassword];
+
 pub struct ListConfigCommand {
     pub is_all: bool,
     pub is_doc: bool,
@@ -32,6 +35,10 @@ impl Command for ListConfigCommand {
 fn print_config_list(config: &TEdgeConfig, all: bool) -> Result<(), ConfigError> {
     let mut keys_without_values = Vec::new();
     for config_key in ReadableKey::iter() {
+        if SECRET_KEYS.contains(&config_key) {
+            keys_without_values.push(config_key);
+            continue;
+        }
         match config.read_string(config_key).ok() {
             Some(value) => {
                 println!("{}={}", config_key, value);
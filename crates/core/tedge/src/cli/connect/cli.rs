@@ -12,7 +12,7 @@ pub enum TEdgeConnectOpt {
     ///
     /// The command will create config and start edge relay from the device to c8y instance
     C8y {
-        /// Test connection to Cumulocity
+        /// Test connection to Cumulocity, including the data path through the mapper
         #[clap(long = "test")]
         is_test_connection: bool,
 
@@ -6,6 +6,7 @@ use crate::bridge::BridgeLocation;
 use crate::bridge::CommonMosquittoConfig;
 use crate::cli::common::Cloud;
 use crate::cli::connect::jwt_token::*;
+use crate::cli::connect::test_measurement::*;
 use crate::cli::connect::*;
 use crate::command::Command;
 use crate::ConfigError;
@@ -33,6 +34,7 @@ use crate::bridge::C8Y_CONFIG_FILENAME;
 use crate::bridge::TEDGE_BRIDGE_CONF_DIR_PATH;
 
 const WAIT_FOR_CHECK_SECONDS: u64 = 2;
+const DATA_PATH_TIMEOUT: Duration = Duration::from_secs(5);
 pub(crate) const RESPONSE_TIMEOUT: Duration = Duration::from_secs(10);
 pub(crate) const CONNECTION_TIMEOUT: Duration = Duration::from_secs(60);
 const MOSQUITTO_RESTART_TIMEOUT_SECONDS: u64 = 5;
@@ -74,6 +76,11 @@ impl Command for ConnectCommand {
                     Ok(DeviceStatus::AlreadyExists) => {
                         let cloud = bridge_config.cloud_name;
                         println!("Connection check to {} cloud is successful.\n", cloud);
+                        if let Cloud::C8y = self.cloud {
+                            if bridge_config.use_mapper {
+                                self.check_data_path(config)?;
+                            }
+                        }
                         Ok(())
                     }
                     Ok(DeviceStatus::Unknown) => Err(ConnectError::UnknownDeviceStatus.into()),
@@ -164,6 +171,27 @@ impl ConnectCommand {
         }
     }
 
+    fn check_data_path(&self, config: &TEdgeConfig) -> Result<(), ConnectError> {
+        let device_id = config
+            .device
+            .id
+            .try_read(config)
+            .map_err(ConfigError::from)?;
+        println!(
+            "Sending a test measurement of type '{}' for device '{}'. This may take up to {} seconds.\n",
+            TEST_MEASUREMENT_TYPE,
+            device_id,
+            2 * DATA_PATH_TIMEOUT.as_secs()
+        );
+        check_c8y_data_path(config, device_id, DATA_PATH_TIMEOUT)?;
+        println!("The test measurement has been forwarded to Cumulocity with no error reported.\n");
+        match delete_test_measurements(config, device_id) {
+            Ok(()) => println!("The test measurement has been deleted from Cumulocity.\n"),
+            Err(err) => println!("Warning: {err}\n"),
+        }
+        Ok(())
+    }
+
     fn check_if_bridge_exists(&self, br_config: &BridgeConfig) -> bool {
         let bridge_conf_path = self
             .config_location
@@ -51,6 +51,24 @@ pub enum ConnectError {
     #[error("Unknown device status")]
     UnknownDeviceStatus,
 
+    #[error("The test measurement of device '{device_id}' has not been forwarded to Cumulocity. Is tedge-mapper-c8y running?")]
+    TestMeasurementNotForwarded { device_id: String },
+
+    #[error(
+        "The test measurement of device '{device_id}' has been rejected by the mapper: {reason}"
+    )]
+    TestMeasurementRejectedByMapper { device_id: String, reason: String },
+
+    #[error(
+        "The test measurement of device '{device_id}' has been rejected by Cumulocity: {reason}"
+    )]
+    TestMeasurementRejectedByCloud { device_id: String, reason: String },
+
+    #[error(
+        "The test measurements of device '{device_id}' cannot be deleted from Cumulocity: {reason}"
+    )]
+    TestMeasurementNotDeleted { device_id: String, reason: String },
+
     #[error(
         "The JWT token received from Cumulocity is invalid.\nToken: {token}\nReason: {reason}"
     )]
@@ -9,6 +9,12 @@ use rumqttc::QoS::AtLeastOnce;
 use tedge_config::TEdgeConfig;
 
 pub(crate) fn get_connected_c8y_url(tedge_config: &TEdgeConfig) -> Result<String, ConnectError> {
+    let token = get_jwt_token(tedge_config)?;
+    decode_jwt_token(token.as_str())
+}
+
+/// Request a JWT token from Cumulocity, returning the token without the SmartREST `71` prefix
+pub(crate) fn get_jwt_token(tedge_config: &TEdgeConfig) -> Result<String, ConnectError> {
     let prefix = &tedge_config.c8y.bridge.topic_prefix;
     let c8y_topic_builtin_jwt_token_upstream = format!("{prefix}/s/uat");
     let c8y_topic_builtin_jwt_token_downstream = format!("{prefix}/s/dat");
@@ -48,8 +54,8 @@ pub(crate) fn get_connected_c8y_url(tedge_config: &TEdgeConfig) -> Result<String
             Ok(Event::Incoming(Packet::Publish(response))) => {
                 // We got a response
                 let token = String::from_utf8(response.payload.to_vec()).unwrap();
-                let connected_url = decode_jwt_token(token.as_str())?;
-                return Ok(connected_url);
+                let token = token.strip_prefix("71,").unwrap_or(&token);
+                return Ok(token.to_string());
             }
             Ok(Event::Outgoing(Outgoing::PingReq)) => {
                 // No messages have been received for a while
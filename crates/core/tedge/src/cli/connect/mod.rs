@@ -7,3 +7,4 @@ mod cli;
 mod command;
 mod error;
 mod jwt_token;
+mod test_measurement;
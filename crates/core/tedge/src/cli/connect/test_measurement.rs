@@ -0,0 +1,416 @@
+use crate::cli::connect::jwt_token::decode_jwt_token;
+use crate::cli::connect::jwt_token::get_jwt_token;
+use crate::cli::connect::ConnectError;
+use anyhow::Context;
+use rumqttc::Event;
+use rumqttc::Incoming;
+use rumqttc::Packet;
+use rumqttc::QoS::AtLeastOnce;
+use std::time::Duration;
+use std::time::Instant;
+use tedge_config::TEdgeConfig;
+
+/// The type of the measurement sent to test the data path to Cumulocity
+pub(crate) const TEST_MEASUREMENT_TYPE: &str = "tedge_connection_test";
+
+/// Check the data path to Cumulocity, by publishing a test measurement for the main device
+/// and waiting for the mapper to forward it to the cloud.
+///
+/// The test fails if the mapper reports an error for this measurement on `te/errors`,
+/// or if Cumulocity reports an error related to this measurement on `c8y/error`, within the given timeout.
+/// The measurement is not retained, so nothing is left on the local broker.
+pub(crate) fn check_c8y_data_path(
+    tedge_config: &TEdgeConfig,
+    device_id: &str,
+    timeout: Duration,
+) -> Result<(), ConnectError> {
+    let root = &tedge_config.mqtt.topic_root;
+    let device_topic_id = &tedge_config.mqtt.device_topic_id;
+    let prefix = &tedge_config.c8y.bridge.topic_prefix;
+    let test_measurement_topic = format!("{root}/{device_topic_id}/m/{TEST_MEASUREMENT_TYPE}");
+    let mapper_output_topic = format!("{prefix}/measurement/measurements/create");
+    let mapper_errors_topic = format!("{root}/errors");
+    let cloud_errors_topic = format!("{prefix}/error");
+    const CLIENT_ID: &str = "check_data_path_c8y";
+
+    let mqtt_options = tedge_config
+        .mqtt_config()?
+        .with_session_name(CLIENT_ID)
+        .with_clean_session(true)
+        .rumqttc_options()?;
+
+    let (mut client, mut connection) = rumqttc::Client::new(mqtt_options, 10);
+    let subscriptions = [
+        &mapper_output_topic,
+        &mapper_errors_topic,
+        &cloud_errors_topic,
+    ];
+    for topic in subscriptions {
+        client.subscribe(topic, AtLeastOnce)?;
+    }
+
+    let mut pending_subscriptions = subscriptions.len();
+    let mut forwarded = false;
+    let mut deadline = Instant::now() + timeout;
+
+    loop {
+        let Some(remaining) = deadline.checked_duration_since(Instant::now()) else {
+            break;
+        };
+        let event = match connection.recv_timeout(remaining) {
+            Ok(event) => event,
+            Err(_) => break,
+        };
+        match event {
+            Ok(Event::Incoming(Packet::SubAck(_))) => {
+                pending_subscriptions -= 1;
+                if pending_subscriptions == 0 {
+                    // We are ready to observe the data path, hence send the measurement
+                    let payload = format!(r#"{{"{TEST_MEASUREMENT_TYPE}":1}}"#);
+                    client.publish(&test_measurement_topic, AtLeastOnce, false, payload)?;
+                }
+            }
+            Ok(Event::Incoming(Packet::Publish(message))) => {
+                let payload = String::from_utf8_lossy(&message.payload).to_string();
+                if message.topic == mapper_output_topic {
+                    if payload.contains(TEST_MEASUREMENT_TYPE) && !forwarded {
+                        // The measurement has been translated and sent to the cloud,
+                        // give Cumulocity the same delay to report an error, if any
+                        forwarded = true;
+                        deadline = Instant::now() + timeout;
+                    }
+                } else if message.topic == mapper_errors_topic {
+                    if payload.contains(TEST_MEASUREMENT_TYPE) {
+                        return Err(ConnectError::TestMeasurementRejectedByMapper {
+                            device_id: device_id.to_string(),
+                            reason: payload,
+                        });
+                    }
+                } else if forwarded && is_test_measurement_error(&payload) {
+                    return Err(ConnectError::TestMeasurementRejectedByCloud {
+                        device_id: device_id.to_string(),
+                        reason: payload,
+                    });
+                }
+            }
+            Ok(Event::Incoming(Incoming::Disconnect)) => {
+                eprintln!("ERROR: Disconnected");
+                break;
+            }
+            Err(err) => {
+                eprintln!("ERROR: {:?}", err);
+                break;
+            }
+            _ => {}
+        }
+    }
+
+    if forwarded {
+        // No error has been reported
+        Ok(())
+    } else if pending_subscriptions == 0 {
+        // The measurement has been sent but not forwarded
+        Err(ConnectError::TestMeasurementNotForwarded {
+            device_id: device_id.to_string(),
+        })
+    } else {
+        // The measurement has not even been sent
+        Err(ConnectError::TimeoutElapsedError)
+    }
+}
+
+/// Cumulocity reports on `c8y/error` the errors of all the requests sent by the device.
+/// Such an error is only related to the test measurement,
+/// if it refers to the test measurement type or to the request used to create measurements.
+fn is_test_measurement_error(payload: &str) -> bool {
+    payload.contains(TEST_MEASUREMENT_TYPE) || payload.contains("measurement/measurements/create")
+}
+
+/// Delete from Cumulocity the test measurements of the device,
+/// using the REST API authenticated with a JWT token of the device
+pub(crate) fn delete_test_measurements(
+    tedge_config: &TEdgeConfig,
+    device_id: &str,
+) -> Result<(), ConnectError> {
+    let token = get_jwt_token(tedge_config)?;
+    let c8y_host = decode_jwt_token(&token)?;
+    let client =
+        c8y_http_client(tedge_config).map_err(|err| ConnectError::TestMeasurementNotDeleted {
+            device_id: device_id.to_string(),
+            reason: format!("{err:#}"),
+        })?;
+    delete_device_measurements(&client, &format!("https://{c8y_host}"), &token, device_id)
+}
+
+/// The HTTP client used to reach Cumulocity
+///
+/// As for the MQTT connection, Cumulocity is trusted using `c8y.root_cert_path`,
+/// the requests being sent through the HTTP proxy and CA configured by `http.proxy`, if any.
+fn c8y_http_client(tedge_config: &TEdgeConfig) -> anyhow::Result<reqwest::blocking::Client> {
+    let mut client = reqwest::blocking::Client::builder();
+    let root_cert = &tedge_config.c8y.root_cert_path;
+    if root_cert.is_file() {
+        let pem = std::fs::read(root_cert)
+            .with_context(|| format!("reading the Cumulocity root certificate: {root_cert}"))?;
+        client = client.add_root_certificate(reqwest::Certificate::from_pem(&pem)?);
+    }
+    if let Some(proxy) = tedge_config.download_proxy()? {
+        client = client.proxy(proxy);
+    }
+    if let Some(root_certificate) = tedge_config.download_root_certificate()? {
+        client = client.add_root_certificate(root_certificate);
+    }
+    Ok(client.build()?)
+}
+
+fn delete_device_measurements(
+    client: &reqwest::blocking::Client,
+    c8y_url: &str,
+    token: &str,
+    device_id: &str,
+) -> Result<(), ConnectError> {
+    let not_deleted = |reason: String| ConnectError::TestMeasurementNotDeleted {
+        device_id: device_id.to_string(),
+        reason,
+    };
+
+    // The measurements are deleted using the internal id of the device
+    let mut identity_url = url::Url::parse(c8y_url).map_err(|err| not_deleted(err.to_string()))?;
+    identity_url
+        .path_segments_mut()
+        .map_err(|()| not_deleted(format!("invalid Cumulocity URL: {c8y_url}")))?
+        .pop_if_empty()
+        .extend(["identity", "externalIds", "c8y_Serial", device_id]);
+    let identity: serde_json::Value = client
+        .get(identity_url)
+        .bearer_auth(token)
+        .send()
+        .and_then(|response| response.error_for_status())
+        .and_then(|response| response.json())
+        .map_err(|err| not_deleted(err.to_string()))?;
+    let source = identity["managedObject"]["id"]
+        .as_str()
+        .ok_or_else(|| not_deleted("the internal id of the device is unknown".to_string()))?;
+
+    client
+        .delete(format!("{c8y_url}/measurement/measurements"))
+        .query(&[("source", source), ("type", TEST_MEASUREMENT_TYPE)])
+        .bearer_auth(token)
+        .send()
+        .and_then(|response| response.error_for_status())
+        .map_err(|err| not_deleted(err.to_string()))?;
+
+    Ok(())
+}
+
+#[cfg(test)]
+mod test {
+    use super::*;
+    use serial_test::serial;
+
+    const TEST_TIMEOUT: Duration = Duration::from_secs(2);
+
+    fn test_config(port: u16, root: &str) -> TEdgeConfig {
+        TEdgeConfig::load_toml_str(&format!(
+            "mqtt.client.port = {port}\nmqtt.topic_root = \"{root}\"\nc8y.bridge.topic_prefix = \"{root}-c8y\""
+        ))
+    }
+
+    // The tests are run in sequence as they share the same MQTT client id
+    #[tokio::test]
+    #[serial]
+    async fn data_path_check_succeeds_when_the_measurement_is_forwarded() {
+        let broker = mqtt_tests::test_mqtt_broker();
+        broker.map_messages_background(|(topic, payload)| match topic.as_str() {
+            "te-ok/device/main///m/tedge_connection_test" => vec![(
+                "te-ok-c8y/measurement/measurements/create".to_string(),
+                format!(r#"{{"type":"tedge_connection_test","input":{payload}}}"#),
+            )],
+            _ => vec![],
+        });
+        // Give the fake mapper time to subscribe
+        tokio::time::sleep(Duration::from_millis(100)).await;
+        let config = test_config(broker.port, "te-ok");
+
+        let result = tokio::task::spawn_blocking(move || {
+            check_c8y_data_path(&config, "test-device", TEST_TIMEOUT)
+        })
+        .await
+        .unwrap();
+
+        assert!(result.is_ok(), "Unexpected error: {result:?}");
+    }
+
+    #[tokio::test]
+    #[serial]
+    async fn data_path_check_fails_when_the_mapper_rejects_the_measurement() {
+        let broker = mqtt_tests::test_mqtt_broker();
+        broker.map_messages_background(|(topic, payload)| match topic.as_str() {
+            "te-rejected/device/main///m/tedge_connection_test" => vec![(
+                "te-rejected/errors".to_string(),
+                format!("Invalid measurement: {payload}"),
+            )],
+            _ => vec![],
+        });
+        // Give the fake mapper time to subscribe
+        tokio::time::sleep(Duration::from_millis(100)).await;
+        let config = test_config(broker.port, "te-rejected");
+
+        let result = tokio::task::spawn_blocking(move || {
+            check_c8y_data_path(&config, "test-device", TEST_TIMEOUT)
+        })
+        .await
+        .unwrap();
+
+        assert_eq!(
+            result.unwrap_err().to_string(),
+            "The test measurement of device 'test-device' has been rejected by the mapper: Invalid measurement: {\"tedge_connection_test\":1}"
+        );
+    }
+
+    #[tokio::test]
+    #[serial]
+    async fn data_path_check_fails_when_cumulocity_rejects_the_measurement() {
+        let broker = mqtt_tests::test_mqtt_broker();
+        broker.map_messages_background(|(topic, payload)| match topic.as_str() {
+            "te-c8y-rejected/device/main///m/tedge_connection_test" => vec![
+                (
+                    "te-c8y-rejected-c8y/measurement/measurements/create".to_string(),
+                    format!(r#"{{"type":"tedge_connection_test","input":{payload}}}"#),
+                ),
+                (
+                    "te-c8y-rejected-c8y/error".to_string(),
+                    r#"{"error":"measurement/measurements/create","message":"Invalid measurement"}"#
+                        .to_string(),
+                ),
+            ],
+            _ => vec![],
+        });
+        // Give the fake mapper time to subscribe
+        tokio::time::sleep(Duration::from_millis(100)).await;
+        let config = test_config(broker.port, "te-c8y-rejected");
+
+        let result = tokio::task::spawn_blocking(move || {
+            check_c8y_data_path(&config, "test-device", TEST_TIMEOUT)
+        })
+        .await
+        .unwrap();
+
+        assert!(matches!(
+            result,
+            Err(ConnectError::TestMeasurementRejectedByCloud { .. })
+        ));
+    }
+
+    #[tokio::test]
+    #[serial]
+    async fn data_path_check_ignores_cumulocity_errors_unrelated_to_the_measurement() {
+        let broker = mqtt_tests::test_mqtt_broker();
+        broker.map_messages_background(|(topic, payload)| match topic.as_str() {
+            "te-unrelated/device/main///m/tedge_connection_test" => vec![
+                (
+                    "te-unrelated-c8y/measurement/measurements/create".to_string(),
+                    format!(r#"{{"type":"tedge_connection_test","input":{payload}}}"#),
+                ),
+                (
+                    "te-unrelated-c8y/error".to_string(),
+                    "45,s/us,Unknown operation".to_string(),
+                ),
+            ],
+            _ => vec![],
+        });
+        // Give the fake mapper time to subscribe
+        tokio::time::sleep(Duration::from_millis(100)).await;
+        let config = test_config(broker.port, "te-unrelated");
+
+        let result = tokio::task::spawn_blocking(move || {
+            check_c8y_data_path(&config, "test-device", TEST_TIMEOUT)
+        })
+        .await
+        .unwrap();
+
+        assert!(result.is_ok(), "Unexpected error: {result:?}");
+    }
+
+    #[tokio::test]
+    #[serial]
+    async fn data_path_check_fails_when_the_mapper_is_not_running() {
+        let broker = mqtt_tests::test_mqtt_broker();
+        let config = test_config(broker.port, "te-no-mapper");
+
+        let result = tokio::task::spawn_blocking(move || {
+            check_c8y_data_path(&config, "test-device", TEST_TIMEOUT)
+        })
+        .await
+        .unwrap();
+
+        assert!(matches!(
+            result,
+            Err(ConnectError::TestMeasurementNotForwarded { .. })
+        ));
+    }
+
+    #[test]
+    fn test_measurements_are_deleted_using_the_device_internal_id() {
+        let client = reqwest::blocking::Client::new();
+        let mut server = mockito::Server::new();
+
+        let _identity = server
+            .mock("GET", "/identity/externalIds/c8y_Serial/test-device")
+            .match_header("authorization", "Bearer test-token")
+            .with_body(r#"{"externalId":"test-device","managedObject":{"id":"1234"}}"#)
+            .create();
+        let deletion = server
+            .mock("DELETE", "/measurement/measurements")
+            .match_query(mockito::Matcher::AllOf(vec![
+                mockito::Matcher::UrlEncoded("source".into(), "1234".into()),
+                mockito::Matcher::UrlEncoded("type".into(), "tedge_connection_test".into()),
+            ]))
+            .match_header("authorization", "Bearer test-token")
+            .with_status(204)
+            .create();
+
+        delete_device_measurements(&client, &server.url(), "test-token", "test-device").unwrap();
+
+        deletion.assert();
+    }
+
+    #[test]
+    fn device_ids_are_url_encoded() {
+        let client = reqwest::blocking::Client::new();
+        let mut server = mockito::Server::new();
+
+        let identity = server
+            .mock("GET", "/identity/externalIds/c8y_Serial/my%2Fdevice%20id")
+            .with_body(r#"{"externalId":"my/device id","managedObject":{"id":"1234"}}"#)
+            .create();
+        let _deletion = server
+            .mock("DELETE", "/measurement/measurements")
+            .match_query(mockito::Matcher::Any)
+            .with_status(204)
+            .create();
+
+        delete_device_measurements(&client, &server.url(), "test-token", "my/device id").unwrap();
+
+        identity.assert();
+    }
+
+    #[test]
+    fn test_measurements_cannot_be_deleted_for_an_unknown_device() {
+        let client = reqwest::blocking::Client::new();
+        let mut server = mockito::Server::new();
+
+        let _identity = server
+            .mock("GET", "/identity/externalIds/c8y_Serial/test-device")
+            .with_status(404)
+            .create();
+
+        let result =
+            delete_device_measurements(&client, &server.url(), "test-token", "test-device");
+
+        assert!(matches!(
+            result,
+            Err(ConnectError::TestMeasurementNotDeleted { .. })
+        ));
+    }
+}
@@ -90,7 +90,7 @@ pub trait Command {
     ///
     /// impl SomeStruct {
     ///     fn build_command(self, config: TEdgeConfig) -> Result<Box<dyn Command>, ConfigError> {
-    ///         let cmd = GetConfigCommand { config, key: ReadableKey::MqttBindPort };
+    ///         let cmd = GetConfigCommand { config, key: ReadableKey::MqttBindPort, is_default: false };
     ///         Ok(cmd.into_boxed())
     ///     }
     /// }
@@ -135,6 +135,7 @@ pub trait Command {
 ///             ConfigCmd::Get { key } => GetConfigCommand {
 ///                 config: context.load_config()?,
 ///                 key,
+///                 is_default: false,
 ///             }.into_boxed(),
 ///         };
 ///         Ok(cmd)
@@ -268,6 +268,222 @@ mod tests {
         Ok(())
     }
 
+    #[test]
+    fn run_config_get_default_and_source() -> Result<(), Box<dyn std::error::Error>> {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let test_home_str = temp_dir.path().to_str().unwrap();
+
+        let mut get_source_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "mqtt.bind.port",
+            "--source",
+        ])?;
+
+        get_source_command
+            .assert()
+            .success()
+            .stdout(predicate::str::diff("default\n"));
+
+        let mut set_config_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "set",
+            "mqtt.bind.port",
+            "8880",
+        ])?;
+
+        set_config_command.assert().success();
+
+        let mut get_default_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "mqtt.bind.port",
+            "--default",
+        ])?;
+
+        get_default_command
+            .assert()
+            .success()
+            .stdout(predicate::str::diff("1883\n"));
+
+        let mut get_source_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "mqtt.bind.port",
+            "--source",
+        ])?;
+
+        get_source_command
+            .assert()
+            .success()
+            .stdout(predicate::str::contains("TOML file"))
+            .stdout(predicate::str::contains("tedge.toml"));
+
+        let mut get_source_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "mqtt.bridge.built_in",
+            "--source",
+        ])?;
+
+        get_source_command
+            .env("TEDGE_MQTT_BRIDGE_BUILT_IN", "true")
+            .assert()
+            .success()
+            .stdout(predicate::str::contains(
+                "environment variable TEDGE_MQTT_BRIDGE_BUILT_IN",
+            ));
+
+        let mut get_default_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "c8y.url",
+            "--default",
+        ])?;
+
+        get_default_command
+            .assert()
+            .success()
+            .stderr(predicate::str::contains(
+                "The provided config key: 'c8y.url' has no default value",
+            ));
+
+        let mut get_source_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "c8y.url",
+            "--source",
+        ])?;
+
+        get_source_command
+            .assert()
+            .success()
+            .stderr(predicate::str::contains(
+                "The provided config key: 'c8y.url' is not set",
+            ));
+
+        Ok(())
+    }
+
+    #[test]
+    fn run_config_set_get_unset_list_key() -> Result<(), Box<dyn std::error::Error>> {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let test_home_str = temp_dir.path().to_str().unwrap();
+
+        let mut set_config_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "set",
+            "c8y.proxy.trusted_hosts",
+            r#"["a.com","b.com"]"#,
+        ])?;
+
+        set_config_command.assert().success();
+
+        let mut get_config_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "c8y.proxy.trusted_hosts",
+        ])?;
+
+        get_config_command
+            .assert()
+            .success()
+            .stdout(predicate::str::diff("[\"a.com\", \"b.com\"]\n"));
+
+        let mut get_config_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "c8y.proxy.trusted_hosts",
+        ])?;
+
+        get_config_command
+            .env("TEDGE_C8Y_PROXY_TRUSTED_HOSTS", "c.com,d.com")
+            .assert()
+            .success()
+            .stdout(predicate::str::diff("[\"c.com\", \"d.com\"]\n"));
+
+        let mut unset_config_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "unset",
+            "c8y.proxy.trusted_hosts",
+        ])?;
+
+        unset_config_command.assert().success();
+
+        let mut get_config_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "c8y.proxy.trusted_hosts",
+        ])?;
+
+        get_config_command
+            .assert()
+            .success()
+            .stdout(predicate::str::diff("[]\n"));
+
+        Ok(())
+    }
+
+    #[test]
+    fn run_config_set_malformed_list_key() -> Result<(), Box<dyn std::error::Error>> {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let test_home_str = temp_dir.path().to_str().unwrap();
+
+        let mut set_config_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "set",
+            "c8y.proxy.trusted_hosts",
+            r#"["a.com", b.com]"#,
+        ])?;
+
+        set_config_command
+            .assert()
+            .failure()
+            .stderr(predicate::str::contains("Failed to parse input"));
+
+        // The malformed value has not been stored
+        let mut get_config_command = tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "c8y.proxy.trusted_hosts",
+        ])?;
+
+        get_config_command
+            .assert()
+            .success()
+            .stdout(predicate::str::diff("[]\n"));
+
+        Ok(())
+    }
+
     #[test]
     fn run_config_defaults() -> Result<(), Box<dyn std::error::Error>> {
         let temp_dir = tempfile::tempdir().unwrap();
@@ -368,6 +584,47 @@ mod tests {
         assert!(cert_path.contains(test_home_str));
     }
 
+    #[test]
+    fn run_config_list_hides_the_proxy_password() {
+        let temp_dir = tempfile::tempdir().unwrap();
+        let test_home_str = temp_dir.path().to_str().unwrap();
+
+        tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "set",
+            "http.proxy.password",
+            "secret",
+        ])
+        .unwrap()
+        .assert()
+        .success();
+
+        for list_args in [vec!["config", "list"], vec!["config", "list", "--all"]] {
+            let mut args = vec!["--config-dir", test_home_str];
+            args.extend(list_args);
+            tedge_command_with_test_home(args)
+                .unwrap()
+                .assert()
+                .success()
+                .stdout(predicate::str::contains("secret").not());
+        }
+
+        // The password can still be read explicitly
+        tedge_command_with_test_home([
+            "--config-dir",
+            test_home_str,
+            "config",
+            "get",
+            "http.proxy.password",
+        ])
+        .unwrap()
+        .assert()
+        .success()
+        .stdout(predicate::str::contains("secret"));
+    }
+
     fn extract_config_value<'a>(output: &'a str, key: &str) -> &'a str {
         output
             .lines()
@@ -18,13 +18,16 @@ use camino::Utf8PathBuf;
 use flockfile::check_another_instance_is_not_running;
 use flockfile::Flockfile;
 use flockfile::FlockfileError;
+use reqwest::Certificate;
 use reqwest::Identity;
+use reqwest::Proxy;
 use std::fmt::Debug;
 use std::net::SocketAddr;
 use std::sync::Arc;
 use tedge_actors::Concurrent;
 use tedge_actors::ConvertingActor;
 use tedge_actors::ConvertingActorBuilder;
+use tedge_actors::Message;
 use tedge_actors::MessageSink;
 use tedge_actors::MessageSource;
 use tedge_actors::NoConfig;
@@ -78,6 +81,8 @@ pub(crate) struct AgentConfig {
     pub tedge_http_host: Arc<str>,
     pub service: TEdgeConfigReaderService,
     pub identity: Option<Identity>,
+    pub download_proxy: Option<Proxy>,
+    pub download_root_certificate: Option<Certificate>,
     pub fts_url: Arc<str>,
     pub is_sudo_enabled: bool,
     pub capabilities: Capabilities,
@@ -149,6 +154,8 @@ impl AgentConfig {
         let operations_dir = config_dir.join("operations");
 
         let identity = tedge_config.http.client.auth.identity()?;
+        let download_proxy = tedge_config.download_proxy()?;
+        let download_root_certificate = tedge_config.download_root_certificate()?;
 
         let is_sudo_enabled = tedge_config.sudo.enable;
 
@@ -181,6 +188,8 @@ impl AgentConfig {
             mqtt_device_topic_id,
             tedge_http_host,
             identity,
+            download_proxy,
+            download_root_certificate,
             fts_url,
             is_sudo_enabled,
             service: tedge_config.service.clone(),
@@ -277,8 +286,12 @@ impl Agent {
         let tedge_to_te_converter = create_tedge_to_te_converter(&mut mqtt_actor_builder)?;
 
         let mut fs_watch_actor_builder = FsWatchActorBuilder::new();
-        let mut downloader_actor_builder =
-            DownloaderActor::new(self.config.identity.clone()).builder();
+        let mut downloader_actor_builder = downloader_actor(
+            self.config.identity.clone(),
+            self.config.download_proxy,
+            self.config.download_root_certificate,
+        )
+        .builder();
         let mut uploader_actor_builder = UploaderActor::new(self.config.identity).builder();
 
         // Instantiate config manager actor if config_snapshot or both operations are enabled
@@ -402,3 +415,175 @@ pub fn create_tedge_to_te_converter(
 
     Ok(tedge_converter_actor)
 }
+
+/// The downloader used by the agent, notably to fetch configuration updates
+///
+/// Requests to external hosts are sent through the HTTP proxy, if any,
+/// while requests to the local services, as the Cumulocity HTTP proxy, are sent directly.
+fn downloader_actor<T: Message + Default>(
+    identity: Option<Identity>,
+    proxy: Option<Proxy>,
+    root_certificate: Option<Certificate>,
+) -> DownloaderActor<T> {
+    DownloaderActor::new(identity)
+        .with_proxy(proxy)
+        .with_root_certificate(root_certificate)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use axum::routing::get;
+    use axum::Router;
+    use std::time::Duration;
+    use tedge_actors::ClientMessageBox;
+    use tedge_config::TEdgeConfig;
+    use tedge_downloader_ext::DownloadRequest;
+    use tedge_downloader_ext::DownloadResult;
+    use tedge_test_utils::fs::TempTedgeDir;
+    use tokio::io::AsyncBufReadExt;
+    use tokio::io::AsyncWriteExt;
+    use tokio::io::BufReader;
+    use tokio::net::TcpListener;
+    use tokio::task::JoinHandle;
+
+    const TEST_TIMEOUT: Duration = Duration::from_secs(5);
+
+    #[tokio::test]
+    async fn external_downloads_are_sent_through_the_proxy_without_device_credentials() {
+        let (proxy_port, proxy) = spawn_fake_proxy().await;
+        let config = TEdgeConfig::load_toml_str(&format!(
+            "http.proxy.address = \"http://127.0.0.1:{proxy_port}\"\n\
+             http.proxy.username = \"tedge\"\n\
+             http.proxy.password = \"secret\""
+        ));
+
+        let ttd = TempTedgeDir::new();
+        let result = download(&config, "http://example.com/config.toml", &ttd).await;
+
+        assert!(result.is_ok(), "Unexpected error: {result:?}");
+        assert_eq!(downloaded_content(&ttd), "from proxy");
+
+        let request = proxy.await.unwrap();
+        assert_eq!(request[0], "GET http://example.com/config.toml HTTP/1.1");
+        let has_header = |name: &str| {
+            request
+                .iter()
+                .any(|line| line.to_ascii_lowercase().starts_with(name))
+        };
+        assert!(has_header("proxy-authorization: basic dgvkz2u6c2vjcmv0"));
+        assert!(!has_header("authorization:"));
+    }
+
+    #[tokio::test]
+    async fn downloads_via_the_cumulocity_http_proxy_are_not_sent_through_the_proxy() {
+        let (proxy_port, proxy) = spawn_fake_proxy().await;
+        let mut c8y_http_proxy = mockito::Server::new_async().await;
+        let _mock = c8y_http_proxy
+            .mock("GET", "/c8y/inventory/binaries/1234")
+            .with_body("from cumulocity")
+            .create_async()
+            .await;
+        let config = TEdgeConfig::load_toml_str(&format!(
+            "http.proxy.address = \"http://127.0.0.1:{proxy_port}\""
+        ));
+
+        let ttd = TempTedgeDir::new();
+        let url = format!("{}/c8y/inventory/binaries/1234", c8y_http_proxy.url());
+        let result = download(&config, &url, &ttd).await;
+
+        assert!(result.is_ok(), "Unexpected error: {result:?}");
+        assert_eq!(downloaded_content(&ttd), "from cumulocity");
+        assert!(!proxy.is_finished(), "The proxy has been used");
+    }
+
+    #[tokio::test]
+    async fn downloads_from_servers_signed_by_the_configured_ca_are_trusted() {
+        let ttd = TempTedgeDir::new();
+        let server_cert = rcgen::generate_simple_self_signed(["localhost".into()]).unwrap();
+        let port = spawn_https_server(&server_cert).await;
+        let url = format!("https://localhost:{port}/config.toml");
+        ttd.file("ca.pem")
+            .with_raw_content(&server_cert.serialize_pem().unwrap());
+
+        let config = TEdgeConfig::load_toml_str(&format!(
+            "http.proxy.ca_path = \"{}\"",
+            ttd.path().join("ca.pem").display()
+        ));
+        let result = download(&config, &url, &ttd).await;
+        assert!(result.is_ok(), "Unexpected error: {result:?}");
+        assert_eq!(downloaded_content(&ttd), "from https server");
+
+        // Without the CA, the server certificate is not trusted
+        let config = TEdgeConfig::load_toml_str("");
+        let result = download(&config, &url, &TempTedgeDir::new()).await;
+        assert!(result.is_err());
+    }
+
+    async fn download(config: &TEdgeConfig, url: &str, ttd: &TempTedgeDir) -> DownloadResult {
+        let mut downloader_actor_builder = downloader_actor(
+            None,
+            config.download_proxy().unwrap(),
+            config.download_root_certificate().unwrap(),
+        )
+        .builder();
+        let mut requester: ClientMessageBox<(String, DownloadRequest), (String, DownloadResult)> =
+            ClientMessageBox::new(&mut downloader_actor_builder);
+        tokio::spawn(downloader_actor_builder.run());
+
+        let request = DownloadRequest::new(url, &ttd.path().join("downloaded_file"));
+        let (_, result) = tokio::time::timeout(
+            TEST_TIMEOUT,
+            requester.await_response(("id".to_string(), request)),
+        )
+        .await
+        .expect("timeout")
+        .expect("channel error");
+        result
+    }
+
+    fn downloaded_content(ttd: &TempTedgeDir) -> String {
+        std::fs::read_to_string(ttd.path().join("downloaded_file")).unwrap()
+    }
+
+    /// Spawn an HTTP proxy responding to a single request, returning the request lines it received
+    async fn spawn_fake_proxy() -> (u16, JoinHandle<Vec<String>>) {
+        let listener = TcpListener::bind("127.0.0.1:0").await.unwrap();
+        let port = listener.local_addr().unwrap().port();
+        let proxy = tokio::spawn(async move {
+            let (mut stream, _addr) = listener.accept().await.unwrap();
+            let (reader, mut writer) = stream.split();
+            let mut lines = BufReader::new(reader).lines();
+
+            let mut request = vec![];
+            while let Ok(Some(line)) = lines.next_line().await {
+                if line.is_empty() {
+                    break;
+                }
+                request.push(line);
+            }
+
+            let msg =
+                "HTTP/1.1 200 OK\r\ncontent-length: 10\r\nconnection: close\r\n\r\nfrom proxy";
+            writer.write_all(msg.as_bytes()).await.unwrap();
+            request
+        });
+        (port, proxy)
+    }
+
+    async fn spawn_https_server(server_cert: &rcgen::Certificate) -> u16 {
+        let listener = TcpListener::bind("127.0.0.1:0").await.unwrap();
+        let port = listener.local_addr().unwrap().port();
+        let config = axum_tls::ssl_config(
+            vec![server_cert.serialize_der().unwrap()],
+            server_cert.serialize_private_key_der(),
+            None,
+        )
+        .unwrap();
+        let app = Router::new().route("/config.toml", get(|| async { "from https server" }));
+
+        let server = axum_tls::start_tls_server(listener.into_std().unwrap(), config, app);
+        tokio::spawn(server);
+        port
+    }
+}
@@ -1,6 +1,7 @@
 use std::convert::Infallible;
 use std::net::IpAddr;
 
+use anyhow::Context;
 use axum::async_trait;
 use c8y_http_proxy::credentials::C8YJwtRetriever;
 use c8y_http_proxy::credentials::JwtRetriever;
@@ -45,6 +46,7 @@ impl C8yAuthProxyBuilder {
             is_https: true,
             host: config.c8y.http.or_config_not_set()?.to_string(),
             token_manager: TokenManager::new(JwtRetriever::new(jwt)).shared(),
+            client: http_client(config)?,
         };
         let bind = &config.c8y.proxy.bind;
         let (signal_sender, signal_receiver) = mpsc::channel(10);
@@ -65,6 +67,20 @@ impl C8yAuthProxyBuilder {
     }
 }
 
+/// The HTTP client used to reach Cumulocity, through the configured HTTP proxy and CA, if any
+fn http_client(config: &TEdgeConfig) -> anyhow::Result<reqwest::Client> {
+    let mut client = reqwest::Client::builder();
+    if let Some(proxy) = config.download_proxy()? {
+        client = client.proxy(proxy);
+    }
+    if let Some(root_certificate) = config.download_root_certificate()? {
+        client = client.add_root_certificate(root_certificate);
+    }
+    client
+        .build()
+        .context("building the HTTP client used to reach Cumulocity")
+}
+
 impl Builder<C8yAuthProxy> for C8yAuthProxyBuilder {
     type Error = Infallible;
 
@@ -133,12 +133,14 @@ pub(crate) struct AppData {
     pub is_https: bool,
     pub host: String,
     pub token_manager: SharedTokenManager,
+    pub client: reqwest::Client,
 }
 
 #[derive(Clone)]
 struct AppState {
     target_host: TargetHost,
     token_manager: SharedTokenManager,
+    client: reqwest::Client,
 }
 
 impl From<AppData> for AppState {
@@ -156,6 +158,7 @@ impl From<AppData> for AppState {
                 without_scheme: host.into(),
             },
             token_manager: value.token_manager,
+            client: value.client,
         }
     }
 }
@@ -172,6 +175,12 @@ impl FromRef<AppState> for SharedTokenManager {
     }
 }
 
+impl FromRef<AppState> for reqwest::Client {
+    fn from_ref(input: &AppState) -> Self {
+        input.client.clone()
+    }
+}
+
 #[derive(Clone)]
 struct TargetHost {
     http: Arc<str>,
@@ -373,6 +382,7 @@ where
 async fn respond_to(
     State(host): State<TargetHost>,
     retrieve_token: State<SharedTokenManager>,
+    State(client): State<reqwest::Client>,
     path: Option<Path<String>>,
     uri: hyper::Uri,
     method: Method,
@@ -409,7 +419,6 @@ async fn respond_to(
         let path = path.to_owned();
         return Ok(ws.on_upgrade(|socket| proxy_ws(socket, host, retrieve_token, headers, path)));
     }
-    let client = reqwest::Client::new();
     let (body, body_clone) = small_body.try_clone();
     if body_clone.is_none() {
         let destination = format!("{}/tenant/currentTenant", host.http);
@@ -491,8 +500,10 @@ mod tests {
     use tedge_actors::Server;
     use tedge_actors::ServerActorBuilder;
     use tedge_actors::ServerConfig;
+    use tokio::io::AsyncBufReadExt;
     use tokio::io::AsyncReadExt;
     use tokio::io::AsyncWriteExt;
+    use tokio::io::BufReader;
     use tokio::net::TcpStream;
     use tokio_tungstenite::tungstenite::protocol::frame::coding::CloseCode;
     use tokio_tungstenite::tungstenite::protocol::CloseFrame;
@@ -1071,6 +1082,126 @@ mod tests {
         assert_eq!(res.bytes().await.unwrap(), Bytes::from("Succeeded"));
     }
 
+    #[tokio::test]
+    async fn sends_requests_to_cumulocity_through_the_configured_http_proxy() {
+        let _ = env_logger::try_init();
+        let (proxy_port, http_proxy) = spawn_fake_http_proxy().await;
+        let proxy = reqwest::Proxy::all(format!("http://127.0.0.1:{proxy_port}"))
+            .unwrap()
+            .basic_auth("tedge", "secret");
+        let client = reqwest::Client::builder().proxy(proxy).build().unwrap();
+
+        let port = start_proxy_with_client("tenant.example.com", false, client, vec!["test-token"]);
+
+        let client = reqwest::Client::builder()
+            .danger_accept_invalid_certs(true)
+            .build()
+            .unwrap();
+        let res = client
+            .get(format!(
+                "https://localhost:{port}/c8y/inventory/binaries/1234"
+            ))
+            .send()
+            .await
+            .unwrap();
+        assert_eq!(res.status(), 200);
+        assert_eq!(res.bytes().await.unwrap(), Bytes::from("from proxy"));
+
+        // The request to the tenant is sent to the HTTP proxy, with the device credentials
+        let request = http_proxy.await.unwrap();
+        let has_line = |expected: &str| {
+            request
+                .iter()
+                .any(|line| line.eq_ignore_ascii_case(expected))
+        };
+        assert!(has_line(
+            "GET http://tenant.example.com/inventory/binaries/1234 HTTP/1.1"
+        ));
+        assert!(has_line("authorization: Bearer test-token"));
+        assert!(has_line("proxy-authorization: Basic dGVkZ2U6c2VjcmV0"));
+    }
+
+    #[tokio::test]
+    async fn trusts_the_configured_ca_certificates_to_connect_to_cumulocity() {
+        let _ = env_logger::try_init();
+        let certificate = rcgen::generate_simple_self_signed(["localhost".to_owned()]).unwrap();
+        let ca = reqwest::Certificate::from_der(&certificate.serialize_der().unwrap()).unwrap();
+        let target_port = spawn_https_target(&certificate);
+        let target_host = format!("localhost:{target_port}");
+
+        let client = reqwest::Client::builder()
+            .danger_accept_invalid_certs(true)
+            .build()
+            .unwrap();
+
+        let trusting_client = reqwest::Client::builder()
+            .add_root_certificate(ca)
+            .build()
+            .unwrap();
+        let port = start_proxy_with_client(&target_host, true, trusting_client, vec!["test-token"]);
+        let res = client
+            .get(format!("https://localhost:{port}/c8y/hello"))
+            .send()
+            .await
+            .unwrap();
+        assert_eq!(res.status(), 200);
+        assert_eq!(res.bytes().await.unwrap(), Bytes::from("Succeeded"));
+
+        // Without the CA, the certificate of the tenant is not trusted
+        let port = start_proxy_with_client(
+            &target_host,
+            true,
+            reqwest::Client::new(),
+            vec!["test-token"],
+        );
+        let res = client
+            .get(format!("https://localhost:{port}/c8y/hello"))
+            .send()
+            .await
+            .unwrap();
+        assert_eq!(res.status(), 502);
+    }
+
+    /// Spawn an HTTP proxy responding to a single request, returning the request lines it received
+    async fn spawn_fake_http_proxy() -> (u16, tokio::task::JoinHandle<Vec<String>>) {
+        let listener = tokio::net::TcpListener::bind("127.0.0.1:0").await.unwrap();
+        let port = listener.local_addr().unwrap().port();
+        let proxy = tokio::spawn(async move {
+            let (mut stream, _addr) = listener.accept().await.unwrap();
+            let (reader, mut writer) = stream.split();
+            let mut lines = BufReader::new(reader).lines();
+
+            let mut request = vec![];
+            while let Ok(Some(line)) = lines.next_line().await {
+                if line.is_empty() {
+                    break;
+                }
+                request.push(line);
+            }
+
+            let msg =
+                "HTTP/1.1 200 OK\r\ncontent-length: 10\r\nconnection: close\r\n\r\nfrom proxy";
+            writer.write_all(msg.as_bytes()).await.unwrap();
+            request
+        });
+        (port, proxy)
+    }
+
+    /// Spawn an HTTPS server using the given certificate, responding to `/hello`
+    fn spawn_https_target(certificate: &rcgen::Certificate) -> u16 {
+        let listener = TcpListener::bind("127.0.0.1:0").unwrap();
+        let port = listener.local_addr().unwrap().port();
+        let config = axum_tls::ssl_config(
+            vec![certificate.serialize_der().unwrap()],
+            certificate.serialize_private_key_der(),
+            None,
+        )
+        .unwrap();
+        let app = Router::new().route("/hello", get(|| async { "Succeeded" }));
+        tokio::spawn(start_tls_server(listener, config, app));
+        port
+    }
+
     fn start_server(server: &mockito::Server, tokens: Vec<impl Into<Cow<'static, str>>>) -> u16 {
         start_server_with_certificate(
             server,
@@ -1105,14 +1236,49 @@ mod tests {
         tokens: Vec<impl Into<Cow<'static, str>>>,
         certificate: rcgen::Certificate,
         ca_dir: Option<Utf8PathBuf>,
+    ) -> u16 {
+        start_proxy(
+            target_host,
+            false,
+            reqwest::Client::new(),
+            tokens,
+            certificate,
+            ca_dir,
+        )
+    }
+
+    fn start_proxy_with_client(
+        target_host: &str,
+        is_https: bool,
+        client: reqwest::Client,
+        tokens: Vec<impl Into<Cow<'static, str>>>,
+    ) -> u16 {
+        start_proxy(
+            target_host,
+            is_https,
+            client,
+            tokens,
+            rcgen::generate_simple_self_signed(["localhost".to_owned()]).unwrap(),
+            None,
+        )
+    }
+
+    fn start_proxy(
+        target_host: &str,
+        is_https: bool,
+        client: reqwest::Client,
+        tokens: Vec<impl Into<Cow<'static, str>>>,
+        certificate: rcgen::Certificate,
+        ca_dir: Option<Utf8PathBuf>,
     ) -> u16 {
         let mut retriever = IterJwtRetriever::builder(tokens);
         let mut last_error = None;
         for port in 3000..3100 {
             let state = AppData {
-                is_https: false,
+                is_https,
                 host: target_host.into(),
                 token_manager: TokenManager::new(JwtRetriever::new(&mut retriever)).shared(),
+                client: client.clone(),
             };
             let trust_store = ca_dir
                 .as_ref()
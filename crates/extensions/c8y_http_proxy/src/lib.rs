@@ -35,6 +35,7 @@ mod tests;
 #[derive(Default, Clone)]
 pub struct C8YHttpConfig {
     pub c8y_host: String,
+    pub trusted_hosts: Vec<String>,
     pub device_id: String,
     pub tmp_dir: PathBuf,
     identity: Option<Identity>,
@@ -46,6 +47,7 @@ impl TryFrom<&TEdgeConfig> for C8YHttpConfig {
 
     fn try_from(tedge_config: &TEdgeConfig) -> Result<Self, Self::Error> {
         let c8y_host = tedge_config.c8y.http.or_config_not_set()?.to_string();
+        let trusted_hosts = tedge_config.c8y.proxy.trusted_hosts.0.clone();
         let device_id = tedge_config.device.id.try_read(tedge_config)?.to_string();
         let tmp_dir = tedge_config.tmp.path.as_std_path().to_path_buf();
         let identity = tedge_config.http.client.auth.identity()?;
@@ -53,6 +55,7 @@ impl TryFrom<&TEdgeConfig> for C8YHttpConfig {
 
         Ok(Self {
             c8y_host,
+            trusted_hosts,
             device_id,
             tmp_dir,
             identity,
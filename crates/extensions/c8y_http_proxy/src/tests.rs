@@ -355,6 +355,7 @@ async fn retry_internal_id_on_expired_jwt_with_mock() {
 
     let config = C8YHttpConfig {
         c8y_host: target_url.clone(),
+        trusted_hosts: vec![],
         device_id: external_id.into(),
         tmp_dir: tmp_dir.into(),
         identity: None,
@@ -419,6 +420,7 @@ async fn retry_create_event_on_expired_jwt_with_mock() {
 
     let config = C8YHttpConfig {
         c8y_host: target_url.clone(),
+        trusted_hosts: vec![],
         device_id: external_id.into(),
         tmp_dir: tmp_dir.into(),
         identity: None,
@@ -662,6 +664,7 @@ async fn spawn_c8y_http_proxy(
 
     let config = C8YHttpConfig {
         c8y_host,
+        trusted_hosts: vec![],
         device_id,
         tmp_dir,
         identity: None,
@@ -5,6 +5,7 @@ use crate::converter::UploadContext;
 use crate::converter::UploadOperationLog;
 use crate::operations::FtsDownloadOperationType;
 use crate::service_monitor::is_c8y_bridge_established;
+use crate::service_monitor::SERVICE_MONITORING_FILE;
 use async_trait::async_trait;
 use c8y_api::smartrest::smartrest_serializer::fail_operation;
 use c8y_api::smartrest::smartrest_serializer::succeed_static_operation;
@@ -48,6 +49,7 @@ use tracing::error;
 use tracing::warn;
 
 const SYNC_WINDOW: Duration = Duration::from_secs(3);
+const C8Y_CONFIG_DIR: &str = "c8y";
 
 pub type SyncStart = SetTimeout<()>;
 pub type SyncComplete = Timeout<()>;
@@ -157,6 +159,16 @@ impl C8yMapperActor {
             FsWatchEvent::FileCreated(path)
             | FsWatchEvent::FileDeleted(path)
             | FsWatchEvent::Modified(path) => {
+                let service_monitoring_file = self
+                    .converter
+                    .config
+                    .config_dir
+                    .join(SERVICE_MONITORING_FILE);
+                if path.as_path() == service_monitoring_file.as_std_path() {
+                    self.converter.reload_service_monitoring();
+                    return Ok(());
+                }
+
                 // Process inotify events only for the main device at the root operations directory
                 // directly under /etc/tedge/operations/c8y
                 if path.parent() == Some(self.converter.config.ops_dir.as_std_path()) {
@@ -193,6 +205,13 @@ impl C8yMapperActor {
             self.process_mqtt_message(message).await?;
         }
 
+        // The child devices listed by the manifest are only registered once the sync phase is complete,
+        // so the retained registration messages take precedence over the manifest entries
+        let registration_messages = self.converter.register_child_devices_from_manifest().await;
+        for registration_message in registration_messages.into_iter() {
+            self.mqtt_publisher.send(registration_message).await?;
+        }
+
         Ok(())
     }
 
@@ -343,6 +362,10 @@ impl C8yMapperBuilder {
             config.ops_dir.as_std_path().to_path_buf(),
             &box_builder.get_sender(),
         );
+        fs_watcher.connect_sink(
+            config.config_dir.join(C8Y_CONFIG_DIR).into_std_path_buf(),
+            &box_builder.get_sender(),
+        );
         let auth_proxy = ProxyUrlGenerator::new(
             config.auth_proxy_addr.clone(),
             config.auth_proxy_port,
@@ -375,6 +398,8 @@ impl C8yMapperBuilder {
         create_directory_with_defaults(config.ops_dir.as_std_path())?;
         // Create directory for device custom fragments
         create_directory_with_defaults(config.config_dir.join("device"))?;
+        // Create directory for the mapper configuration files
+        create_directory_with_defaults(config.config_dir.join(C8Y_CONFIG_DIR))?;
         // Create directory for persistent entity store
         create_directory_with_defaults(config.state_dir.as_std_path())?;
         Ok(())
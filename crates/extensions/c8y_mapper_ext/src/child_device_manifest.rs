@@ -0,0 +1,206 @@
+//! Bulk registration of child devices
+//!
+//! By default, child devices are registered one by one, as their data or registration messages arrive.
+//! A set of child devices can instead be registered up front, by listing them in a manifest:
+//!
+//! ```json
+//! [
+//!   { "id": "child01", "type": "Raspberry Pi 4", "name": "Packaging line sensor" },
+//!   { "id": "child02" }
+//! ]
+//! ```
+//!
+//! The manifest is read from `/etc/tedge/c8y/child-devices.json` once the mapper sync phase is complete,
+//! and can also be published on demand on the `c8y-internal/child-devices` topic.
+//!
+//! Each child device is registered under the default topic identifier `device/<id>//`,
+//! its name defaulting to its id. Child devices that are already registered are skipped.
+//! An invalid entry is reported as an error, without preventing the other devices to be registered.
+use serde::Deserialize;
+use serde_json::Map;
+use serde_json::Value;
+use std::path::Path;
+use std::path::PathBuf;
+use tedge_api::entity_store::EntityRegistrationMessage;
+use tedge_api::entity_store::EntityType;
+use tedge_api::mqtt_topics::EntityTopicId;
+
+pub const CHILD_DEVICE_MANIFEST_FILE: &str = "c8y/child-devices.json";
+pub const CHILD_DEVICE_MANIFEST_TOPIC: &str = "c8y-internal/child-devices";
+
+#[derive(Debug, thiserror::Error)]
+pub enum ChildDeviceManifestError {
+    #[error(transparent)]
+    FromIo(#[from] std::io::Error),
+
+    #[error("Error while parsing child device manifest file: '{0}': {1}.")]
+    InvalidFile(PathBuf, #[source] serde_json::Error),
+
+    #[error("Invalid child device manifest, expecting an array of child devices: {0}")]
+    InvalidManifest(#[source] serde_json::Error),
+
+    #[error("Invalid child device manifest entry: {entry}: {reason}")]
+    InvalidEntry { entry: String, reason: String },
+}
+
+/// The child devices listed by a manifest
+#[derive(Debug, Default)]
+pub struct ChildDeviceManifest {
+    /// The registration messages of the valid entries, in order
+    pub devices: Vec<EntityRegistrationMessage>,
+
+    /// The errors for the invalid entries
+    pub errors: Vec<ChildDeviceManifestError>,
+}
+
+#[derive(Debug, Deserialize)]
+struct ChildDeviceEntry {
+    id: String,
+
+    #[serde(rename = "type")]
+    device_type: Option<String>,
+
+    name: Option<String>,
+}
+
+impl ChildDeviceManifest {
+    /// Load the manifest from the given file, if any
+    pub fn load(path: impl AsRef<Path>) -> Result<Self, ChildDeviceManifestError> {
+        let path = path.as_ref();
+        match std::fs::read_to_string(path) {
+            Ok(content) => serde_json::from_str(&content)
+                .map(Self::from_entries)
+                .map_err(|e| ChildDeviceManifestError::InvalidFile(path.to_path_buf(), e)),
+            Err(err) if err.kind() == std::io::ErrorKind::NotFound => Ok(Self::default()),
+            Err(err) => Err(err.into()),
+        }
+    }
+
+    /// Parse a manifest received as an MQTT payload
+    pub fn parse(payload: &str) -> Result<Self, ChildDeviceManifestError> {
+        serde_json::from_str(payload)
+            .map(Self::from_entries)
+            .map_err(ChildDeviceManifestError::InvalidManifest)
+    }
+
+    fn from_entries(entries: Vec<Value>) -> Self {
+        let mut manifest = ChildDeviceManifest::default();
+        for entry in entries {
+            match registration_message(&entry) {
+                Ok(device) => manifest.devices.push(device),
+                Err(reason) => manifest
+                    .errors
+                    .push(ChildDeviceManifestError::InvalidEntry {
+                        entry: entry.to_string(),
+                        reason,
+                    }),
+            }
+        }
+        manifest
+    }
+}
+
+fn registration_message(entry: &Value) -> Result<EntityRegistrationMessage, String> {
+    let entry = ChildDeviceEntry::deserialize(entry).map_err(|err| err.to_string())?;
+    if entry.id.is_empty() {
+        return Err("the child device id cannot be empty".to_string());
+    }
+    let topic_id = EntityTopicId::default_child_device(&entry.id).map_err(|err| err.to_string())?;
+
+    let mut other = Map::new();
+    other.insert("name".into(), Value::String(entry.name.unwrap_or(entry.id)));
+    if let Some(device_type) = entry.device_type {
+        other.insert("type".into(), Value::String(device_type));
+    }
+
+    Ok(EntityRegistrationMessage {
+        topic_id,
+        external_id: None,
+        r#type: EntityType::ChildDevice,
+        parent: None,
+        other,
+    })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use tedge_test_utils::fs::TempTedgeDir;
+
+    #[test]
+    fn load_manifest_from_file() {
+        let ttd = TempTedgeDir::new();
+        ttd.dir("c8y").file("child-devices.json").with_raw_content(
+            r#"[
+                { "id": "child01", "type": "Raspberry Pi 4", "name": "Packaging line sensor" },
+                { "id": "child02" }
+            ]"#,
+        );
+
+        let manifest =
+            ChildDeviceManifest::load(ttd.path().join(CHILD_DEVICE_MANIFEST_FILE)).unwrap();
+
+        assert!(manifest.errors.is_empty());
+        let devices: Vec<_> = manifest
+            .devices
+            .iter()
+            .map(|device| {
+                (
+                    device.topic_id.as_str(),
+                    Value::Object(device.other.clone()),
+                )
+            })
+            .collect();
+        assert_eq!(
+            devices,
+            vec![
+                (
+                    "device/child01//",
+                    serde_json::json!({"name": "Packaging line sensor", "type": "Raspberry Pi 4"})
+                ),
+                ("device/child02//", serde_json::json!({"name": "child02"})),
+            ]
+        );
+    }
+
+    #[test]
+    fn missing_manifest_file_means_no_devices() {
+        let ttd = TempTedgeDir::new();
+
+        let manifest =
+            ChildDeviceManifest::load(ttd.path().join(CHILD_DEVICE_MANIFEST_FILE)).unwrap();
+
+        assert!(manifest.devices.is_empty());
+        assert!(manifest.errors.is_empty());
+    }
+
+    #[test]
+    fn invalid_entries_are_reported_without_rejecting_the_others() {
+        let manifest = ChildDeviceManifest::parse(
+            r#"[
+                { "id": "child01" },
+                { "name": "no id" },
+                { "id": "" },
+                { "id": "child/02" },
+                "child03"
+            ]"#,
+        )
+        .unwrap();
+
+        let devices: Vec<_> = manifest
+            .devices
+            .iter()
+            .map(|device| device.topic_id.as_str())
+            .collect();
+        assert_eq!(devices, vec!["device/child01//"]);
+        assert_eq!(manifest.errors.len(), 4);
+    }
+
+    #[test]
+    fn a_manifest_must_be_an_array() {
+        assert!(matches!(
+            ChildDeviceManifest::parse(r#"{ "id": "child01" }"#),
+            Err(ChildDeviceManifestError::InvalidManifest(_))
+        ));
+    }
+}
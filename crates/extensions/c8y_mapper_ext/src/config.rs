@@ -1,3 +1,4 @@
+use crate::child_device_manifest::CHILD_DEVICE_MANIFEST_TOPIC;
 use crate::Capabilities;
 use c8y_api::json_c8y_deserializer::C8yDeviceControlTopic;
 use c8y_api::smartrest::error::OperationsError;
@@ -39,6 +40,7 @@ pub struct C8yMapperConfig {
     pub device_type: String,
     pub service: TEdgeConfigReaderService,
     pub c8y_host: String,
+    pub c8y_trusted_hosts: Vec<String>,
     pub tedge_http_host: Arc<str>,
     pub topics: TopicFilter,
     pub capabilities: Capabilities,
@@ -53,6 +55,7 @@ pub struct C8yMapperConfig {
     pub software_management_api: SoftwareManagementApiFlag,
     pub software_management_with_types: bool,
     pub auto_log_upload: AutoLogUpload,
+    pub compress_config_snapshot: bool,
     pub bridge_service_name: String,
     pub bridge_health_topic: Topic,
 
@@ -77,6 +80,7 @@ impl C8yMapperConfig {
         device_type: String,
         service: TEdgeConfigReaderService,
         c8y_host: String,
+        c8y_trusted_hosts: Vec<String>,
         tedge_http_host: Arc<str>,
         topics: TopicFilter,
         capabilities: Capabilities,
@@ -91,6 +95,7 @@ impl C8yMapperConfig {
         software_management_api: SoftwareManagementApiFlag,
         software_management_with_types: bool,
         auto_log_upload: AutoLogUpload,
+        compress_config_snapshot: bool,
     ) -> Self {
         let ops_dir = config_dir
             .join(SUPPORTED_OPERATIONS_DIRECTORY)
@@ -113,6 +118,7 @@ impl C8yMapperConfig {
             device_type,
             service,
             c8y_host,
+            c8y_trusted_hosts,
             tedge_http_host,
             topics,
             capabilities,
@@ -127,6 +133,7 @@ impl C8yMapperConfig {
             software_management_api,
             software_management_with_types,
             auto_log_upload,
+            compress_config_snapshot,
             bridge_service_name,
             bridge_health_topic,
 
@@ -153,6 +160,7 @@ impl C8yMapperConfig {
         let device_topic_id = EntityTopicId::from_str(&tedge_config.mqtt.device_topic_id)?;
         let service = tedge_config.service.clone();
         let c8y_host = tedge_config.c8y.http.or_config_not_set()?.to_string();
+        let c8y_trusted_hosts = tedge_config.c8y.proxy.trusted_hosts.0.clone();
         let tedge_http_address = tedge_config.http.client.host.clone();
         let tedge_http_port = tedge_config.http.client.port;
         let mqtt_schema = MqttSchema::with_root(tedge_config.mqtt.topic_root.clone());
@@ -184,6 +192,7 @@ impl C8yMapperConfig {
         let software_management_with_types = tedge_config.c8y.software_management.with_types;
 
         let auto_log_upload = tedge_config.c8y.operations.auto_log_upload.clone();
+        let compress_config_snapshot = tedge_config.c8y.config.compress;
 
         // Add feature topic filters
         for cmd in [
@@ -233,6 +242,7 @@ impl C8yMapperConfig {
             device_type,
             service,
             c8y_host,
+            c8y_trusted_hosts,
             tedge_http_host,
             topics,
             capabilities,
@@ -247,6 +257,7 @@ impl C8yMapperConfig {
             software_management_api,
             software_management_with_types,
             auto_log_upload,
+            compress_config_snapshot,
         ))
     }
 
@@ -256,6 +267,7 @@ impl C8yMapperConfig {
     ) -> Result<TopicFilter, C8yMapperConfigError> {
         let mut topic_filter: TopicFilter = vec![
             "c8y-internal/alarms/+/+/+/+/+/a/+",
+            CHILD_DEVICE_MANIFEST_TOPIC,
             C8yTopic::SmartRestRequest.with_prefix(prefix).as_str(),
             &C8yDeviceControlTopic::name(prefix),
         ]
@@ -6,9 +6,14 @@ use super::service_monitor;
 use crate::actor::CmdId;
 use crate::actor::IdDownloadRequest;
 use crate::actor::IdUploadRequest;
+use crate::child_device_manifest::ChildDeviceManifest;
+use crate::child_device_manifest::CHILD_DEVICE_MANIFEST_FILE;
+use crate::child_device_manifest::CHILD_DEVICE_MANIFEST_TOPIC;
 use crate::dynamic_discovery::DiscoverOp;
 use crate::error::ConversionError;
 use crate::json;
+use crate::measurement_templates::MeasurementTemplates;
+use crate::measurement_templates::MEASUREMENT_TEMPLATES_FILE;
 use crate::operations::FtsDownloadOperationData;
 use anyhow::anyhow;
 use anyhow::Context;
@@ -49,12 +54,16 @@ use c8y_auth_proxy::url::ProxyUrlGenerator;
 use c8y_http_proxy::handle::C8YHttpProxy;
 use c8y_http_proxy::messages::CreateEvent;
 use camino::Utf8Path;
+use clock::Clock;
+use clock::WallClock;
 use plugin_sm::operation_logs::OperationLogs;
 use plugin_sm::operation_logs::OperationLogsError;
 use serde_json::json;
 use serde_json::Map;
 use serde_json::Value;
 use service_monitor::convert_health_status_message;
+use service_monitor::ServiceMonitoring;
+use service_monitor::SERVICE_MONITORING_FILE;
 use std::collections::HashMap;
 use std::collections::HashSet;
 use std::fs;
@@ -224,6 +233,10 @@ pub struct CumulocityConverter {
     mqtt_publisher: LoggingSender<MqttMessage>,
     pub http_proxy: C8YHttpProxy,
     pub children: HashMap<String, Operations>,
+    measurement_templates: MeasurementTemplates,
+    service_monitoring: ServiceMonitoring,
+    /// The services on which a `service_down` alarm has been raised by the mapper
+    raised_service_alarms: HashSet<EntityExternalId>,
     pub service_type: String,
     pub c8y_endpoint: C8yEndPoint,
     pub mqtt_schema: MqttSchema,
@@ -267,13 +280,27 @@ impl CumulocityConverter {
 
         let operations = Operations::try_new(&*config.ops_dir)?;
         let children = get_child_ops(&*config.ops_dir)?;
+        // Invalid configuration files must not prevent the mapper to start
+        let measurement_templates =
+            MeasurementTemplates::load(config.config_dir.join(MEASUREMENT_TEMPLATES_FILE))
+                .unwrap_or_else(|err| {
+                    error!("Using no measurement templates: {err}");
+                    MeasurementTemplates::default()
+                });
+        let service_monitoring =
+            ServiceMonitoring::load(config.config_dir.join(SERVICE_MONITORING_FILE))
+                .unwrap_or_else(|err| {
+                    error!("Using the default service monitoring rules: {err}");
+                    ServiceMonitoring::default()
+                });
 
         let alarm_converter = AlarmConverter::new();
 
         let log_dir = config.logs_path.join(TEDGE_AGENT_LOG_DIR);
         let operation_logs = OperationLogs::try_new(log_dir)?;
 
-        let c8y_endpoint = C8yEndPoint::new(&c8y_host, &device_id);
+        let c8y_endpoint = C8yEndPoint::new(&c8y_host, &device_id)
+            .with_trusted_hosts(config.c8y_trusted_hosts.clone());
 
         let mqtt_schema = config.mqtt_schema.clone();
 
@@ -310,6 +337,9 @@ impl CumulocityConverter {
             operation_logs,
             http_proxy,
             children,
+            measurement_templates,
+            service_monitoring,
+            raised_service_alarms: HashSet::new(),
             mqtt_publisher,
             service_type,
             c8y_endpoint,
@@ -462,20 +492,32 @@ impl CumulocityConverter {
         let mut mqtt_messages: Vec<MqttMessage> = Vec::new();
 
         if let Some(entity) = self.entity_store.get(source) {
+            // Custom SmartREST templates can only be used for the main device measurements
+            let template = match entity.r#type {
+                EntityType::MainDevice => self.measurement_templates.get(measurement_type),
+                EntityType::ChildDevice | EntityType::Service => None,
+            };
             // Need to check if the input Thin Edge JSON is valid before adding a child ID to list
             let c8y_json_payload =
                 json::from_thin_edge_json(input.payload_str()?, entity, measurement_type)?;
+            let (topic, payload) = match template {
+                Some(template) => {
+                    let measurement: Value = serde_json::from_str(input.payload_str()?)?;
+                    (
+                        template.topic(&self.config.c8y_prefix),
+                        template.render(&measurement, WallClock.now())?,
+                    )
+                }
+                None => (self.mapper_config.out_topic.clone(), c8y_json_payload),
+            };
 
-            if c8y_json_payload.len() < self.size_threshold.0 {
-                mqtt_messages.push(MqttMessage::new(
-                    &self.mapper_config.out_topic,
-                    c8y_json_payload,
-                ));
+            if payload.len() < self.size_threshold.0 {
+                mqtt_messages.push(MqttMessage::new(&topic, payload));
             } else {
                 return Err(ConversionError::TranslatedSizeExceededThreshold {
                     payload: input.payload_str()?.chars().take(50).collect(),
                     topic: input.topic.name.clone(),
-                    actual_size: c8y_json_payload.len(),
+                    actual_size: payload.len(),
                     threshold: self.size_threshold.0,
                 });
             }
@@ -586,9 +628,23 @@ impl CumulocityConverter {
             &ancestors_external_ids,
             message,
             &self.config.c8y_prefix,
+            &self.service_monitoring,
+            &mut self.raised_service_alarms,
         ))
     }
 
+    /// Reload the service monitoring rules, keeping the current ones if the file is invalid
+    pub fn reload_service_monitoring(&mut self) {
+        let path = self.config.config_dir.join(SERVICE_MONITORING_FILE);
+        match ServiceMonitoring::load(&path) {
+            Ok(service_monitoring) => {
+                info!("Reloaded the service monitoring rules from {path}");
+                self.service_monitoring = service_monitoring;
+            }
+            Err(err) => error!("Keeping the current service monitoring rules: {err}"),
+        }
+    }
+
     async fn parse_c8y_devicecontrol_topic(
         &mut self,
         message: &MqttMessage,
@@ -1063,6 +1119,42 @@ impl CumulocityConverter {
         Ok(mapped_messages)
     }
 
+    /// Register the child devices listed by the manifest file, if any
+    ///
+    /// This function is called on start, after the init messages have been published.
+    pub async fn register_child_devices_from_manifest(&mut self) -> Vec<MqttMessage> {
+        let manifest_path = self.config.config_dir.join(CHILD_DEVICE_MANIFEST_FILE);
+        match ChildDeviceManifest::load(manifest_path) {
+            Ok(manifest) => self.register_child_devices(manifest).await,
+            Err(err) => vec![self.new_error_message(err.into())],
+        }
+    }
+
+    /// Register all the child devices of a manifest, skipping those already registered
+    async fn register_child_devices(&mut self, manifest: ChildDeviceManifest) -> Vec<MqttMessage> {
+        let mut messages: Vec<MqttMessage> = manifest
+            .errors
+            .into_iter()
+            .map(|err| self.new_error_message(err.into()))
+            .collect();
+
+        for device in manifest.devices {
+            if self.entity_store.get(&device.topic_id).is_some() {
+                debug!(
+                    "Skipping the registration of {}: already registered",
+                    device.topic_id
+                );
+                continue;
+            }
+            let result = self
+                .try_register_entity_with_pending_children(&device)
+                .await;
+            messages.append(&mut self.wrap_errors(result));
+        }
+
+        messages
+    }
+
     fn try_auto_register_entity(
         &mut self,
         source: &EntityTopicId,
@@ -1265,7 +1357,10 @@ impl CumulocityConverter {
     ) -> Result<Vec<MqttMessage>, ConversionError> {
         let mut registration_messages = vec![];
         registration_messages.push(self.convert_entity_registration_message(registration_message));
-        if registration_message.r#type == EntityType::Service && channel.is_health() {
+        if registration_message.r#type == EntityType::Service
+            && channel.is_health()
+            && !self.health_status_requires_service_creation(&registration_message.topic_id)
+        {
             // If the auto-registration is done on a health status message,
             // no need to map it to a C8y service creation message here,
             // as the status message itself is mapped into a service creation message
@@ -1280,6 +1375,14 @@ impl CumulocityConverter {
         Ok(registration_messages)
     }
 
+    /// Return true if the health status messages of the given service
+    /// raise alarms on a service managed object that they don't create
+    fn health_status_requires_service_creation(&self, service: &EntityTopicId) -> bool {
+        service
+            .default_service_name()
+            .is_some_and(|name| self.service_monitoring.requires_service_creation(name))
+    }
+
     fn convert_entity_registration_message(
         &self,
         value: &EntityRegistrationMessage,
@@ -1321,6 +1424,10 @@ impl CumulocityConverter {
                 self.alarm_converter.process_internal_alarm(message);
                 Ok(vec![])
             }
+            topic if topic.name == CHILD_DEVICE_MANIFEST_TOPIC => {
+                let manifest = ChildDeviceManifest::parse(message.payload_str()?)?;
+                Ok(self.register_child_devices(manifest).await)
+            }
             topic if C8yDeviceControlTopic::accept(topic, &self.config.c8y_prefix) => {
                 self.parse_c8y_devicecontrol_topic(message).await
             }
@@ -1906,6 +2013,7 @@ pub(crate) mod tests {
     use tedge_config::SoftwareManagementApiFlag;
     use tedge_config::TEdgeConfig;
     use tedge_mqtt_ext::test_helpers::assert_messages_matching;
+    use tedge_mqtt_ext::test_helpers::MessagePayloadMatcher;
     use tedge_mqtt_ext::MqttMessage;
     use tedge_mqtt_ext::Topic;
     use tedge_test_utils::fs::TempTedgeDir;
@@ -2370,6 +2478,137 @@ pub(crate) mod tests {
         );
     }
 
+    #[tokio::test]
+    async fn register_child_devices_from_manifest() {
+        let tmp_dir = TempTedgeDir::new();
+        tmp_dir
+            .dir("c8y")
+            .file("child-devices.json")
+            .with_raw_content(
+                r#"[
+                { "id": "child1", "type": "Raspberry Pi 4", "name": "Packaging line sensor" },
+                { "id": "child2" }
+            ]"#,
+            );
+        let (mut converter, _http_proxy) = create_c8y_converter(&tmp_dir).await;
+
+        let messages = converter.register_child_devices_from_manifest().await;
+
+        assert_messages_matching(
+            &messages,
+            [
+                (
+                    "c8y/s/us",
+                    "101,test-device:device:child1,Packaging line sensor,Raspberry Pi 4".into(),
+                ),
+                (
+                    "te/device/child1//",
+                    json!({
+                        "@id":"test-device:device:child1",
+                        "@type":"child-device",
+                        "name":"Packaging line sensor",
+                        "type":"Raspberry Pi 4",
+                    })
+                    .into(),
+                ),
+                (
+                    "c8y/s/us",
+                    "101,test-device:device:child2,child2,thin-edge.io-child".into(),
+                ),
+                (
+                    "te/device/child2//",
+                    json!({
+                        "@id":"test-device:device:child2",
+                        "@type":"child-device",
+                        "name":"child2",
+                    })
+                    .into(),
+                ),
+            ],
+        );
+        assert_eq!(messages.len(), 4);
+    }
+
+    #[tokio::test]
+    async fn child_devices_already_registered_are_skipped_by_a_manifest() {
+        let tmp_dir = TempTedgeDir::new();
+        let (mut converter, _http_proxy) = create_c8y_converter(&tmp_dir).await;
+
+        let reg_message = MqttMessage::new(
+            &Topic::new_unchecked("te/device/child1//"),
+            json!({
+                "@type":"child-device",
+                "@id":"child1",
+                "name":"child1",
+            })
+            .to_string(),
+        );
+        let _ = converter.convert(&reg_message).await;
+
+        let manifest = MqttMessage::new(
+            &Topic::new_unchecked("c8y-internal/child-devices"),
+            r#"[{ "id": "child1" }, { "id": "child2" }]"#,
+        );
+        let messages = converter.convert(&manifest).await;
+
+        assert_messages_matching(
+            &messages,
+            [
+                (
+                    "c8y/s/us",
+                    "101,test-device:device:child2,child2,thin-edge.io-child".into(),
+                ),
+                ("te/device/child2//", MessagePayloadMatcher::Skip),
+            ],
+        );
+        assert_eq!(messages.len(), 2);
+
+        // Registering the same manifest twice is a no-op
+        let messages = converter.convert(&manifest).await;
+        assert!(messages.is_empty(), "Unexpected messages: {messages:?}");
+    }
+
+    #[tokio::test]
+    async fn malformed_manifest_entries_are_reported_as_errors() {
+        let tmp_dir = TempTedgeDir::new();
+        let (mut converter, _http_proxy) = create_c8y_converter(&tmp_dir).await;
+
+        let manifest = MqttMessage::new(
+            &Topic::new_unchecked("c8y-internal/child-devices"),
+            r#"[{ "name": "no id" }, { "id": "child1" }]"#,
+        );
+        let messages = converter.convert(&manifest).await;
+
+        assert_messages_matching(
+            &messages,
+            [
+                (
+                    "te/errors",
+                    "Invalid child device manifest entry: {\"name\":\"no id\"}: missing field `id`"
+                        .into(),
+                ),
+                (
+                    "c8y/s/us",
+                    "101,test-device:device:child1,child1,thin-edge.io-child".into(),
+                ),
+                ("te/device/child1//", MessagePayloadMatcher::Skip),
+            ],
+        );
+
+        let not_a_manifest = MqttMessage::new(
+            &Topic::new_unchecked("c8y-internal/child-devices"),
+            r#"{ "id": "child2" }"#,
+        );
+        let messages = converter.convert(&not_a_manifest).await;
+        assert_messages_matching(
+            &messages,
+            [(
+                "te/errors",
+                "Invalid child device manifest, expecting an array of child devices".into(),
+            )],
+        );
+    }
+
     #[tokio::test]
     async fn auto_registration_succeeds_even_on_bad_input() {
         let tmp_dir = TempTedgeDir::new();
@@ -2540,6 +2779,67 @@ pub(crate) mod tests {
         assert_eq!(out_messages, vec![expected_c8y_json_message.clone()]);
     }
 
+    #[tokio::test]
+    async fn convert_measurement_with_custom_smartrest_template() {
+        let tmp_dir = TempTedgeDir::new();
+        tmp_dir
+            .dir("c8y")
+            .file("measurement-templates.toml")
+            .with_raw_content(
+                r#"
+            [[templates]]
+            type = "environment"
+            template_id = "tedge-measurements"
+            message_id = "101"
+            fields = ["time", "temperature", "location.altitude"]
+            "#,
+            );
+        let (mut converter, _http_proxy) = create_c8y_converter(&tmp_dir).await;
+
+        let in_topic = "te/device/main///m/environment";
+        let in_payload = r#"{"temperature": 23.5, "location": {"altitude": 512}, "time": "2021-11-16T17:45:40.571760714+01:00"}"#;
+        let in_message = MqttMessage::new(&Topic::new_unchecked(in_topic), in_payload);
+
+        let expected_smartrest_message = MqttMessage::new(
+            &Topic::new_unchecked("c8y/s/uc/tedge-measurements"),
+            "101,2021-11-16T17:45:40.571760714+01:00,23.5,512",
+        );
+
+        let out_messages: Vec<_> = converter
+            .convert(&in_message)
+            .await
+            .into_iter()
+            .filter(|m| m.topic.name.starts_with("c8y"))
+            .collect();
+        assert_eq!(out_messages, vec![expected_smartrest_message]);
+
+        // Invalid thin-edge JSON measurements are rejected, even if a template matches
+        for in_payload in [r#"{"temperature": true}"#, r#"[{"temperature": 23.5}]"#] {
+            let in_message = MqttMessage::new(&Topic::new_unchecked(in_topic), in_payload);
+            let out_messages = converter.convert(&in_message).await;
+            assert_eq!(out_messages.len(), 1);
+            assert_eq!(out_messages[0].topic.name, "te/errors");
+        }
+
+        // Measurement types with no matching templates are sent as JSON
+        let in_topic = "te/device/main///m/test_type";
+        let in_payload = r#"{"temp": 1, "time": "2021-11-16T17:45:40.571760714+01:00"}"#;
+        let in_message = MqttMessage::new(&Topic::new_unchecked(in_topic), in_payload);
+
+        let expected_c8y_json_message = MqttMessage::new(
+            &Topic::new_unchecked("c8y/measurement/measurements/create"),
+            r#"{"temp":{"temp":{"value":1.0}},"time":"2021-11-16T17:45:40.571760714+01:00","type":"test_type"}"#,
+        );
+
+        let out_messages: Vec<_> = converter
+            .convert(&in_message)
+            .await
+            .into_iter()
+            .filter(|m| m.topic.name.starts_with("c8y"))
+            .collect();
+        assert_eq!(out_messages, vec![expected_c8y_json_message]);
+    }
+
     #[tokio::test]
     async fn convert_measurement_with_child_id_with_measurement_type() {
         let tmp_dir = TempTedgeDir::new();
@@ -3215,6 +3515,46 @@ pub(crate) mod tests {
         assert_eq!(smartrest_fields.next().unwrap(), "up");
     }
 
+    #[tokio::test]
+    async fn services_are_created_before_raising_alarms_without_availability() {
+        let tmp_dir = TempTedgeDir::new();
+        tmp_dir
+            .dir("c8y")
+            .file("service-monitoring.toml")
+            .with_raw_content(
+                r#"
+            default = ["alarm"]
+            "#,
+            );
+        let (mut converter, _http_proxy) = create_c8y_converter(&tmp_dir).await;
+
+        let service_health_message = MqttMessage::new(
+            &Topic::new_unchecked("te/device/main/service/service1/status/health"),
+            r#"{"status":"down"}"#,
+        );
+
+        let output = converter.convert(&service_health_message).await;
+        let c8y_messages: Vec<_> = output
+            .iter()
+            .filter(|m| m.topic.name.starts_with("c8y/s/us"))
+            .map(|m| (m.topic.name.as_str(), m.payload_str().unwrap()))
+            .collect();
+
+        assert_eq!(
+            c8y_messages,
+            vec![
+                (
+                    "c8y/s/us",
+                    "102,test-device:device:main:service:service1,service,service1,up"
+                ),
+                (
+                    "c8y/s/us/test-device:device:main:service:service1",
+                    "302,service_down,Service service1 is down"
+                ),
+            ]
+        );
+    }
+
     #[test_case("restart")]
     #[test_case("software_list")]
     #[test_case("software_update")]
@@ -3576,6 +3916,7 @@ pub(crate) mod tests {
             device_type,
             tedge_config.service.clone(),
             c8y_host,
+            vec![],
             tedge_http_host,
             topics,
             Capabilities::default(),
@@ -3590,6 +3931,7 @@ pub(crate) mod tests {
             SoftwareManagementApiFlag::Advanced,
             true,
             AutoLogUpload::Never,
+            false,
         )
     }
 
@@ -134,6 +134,9 @@ pub enum ConversionError {
 
     #[error(transparent)]
     ChannelError(#[from] tedge_actors::ChannelError),
+
+    #[error(transparent)]
+    FromChildDeviceManifestError(#[from] crate::child_device_manifest::ChildDeviceManifestError),
 }
 
 #[derive(thiserror::Error, Debug)]
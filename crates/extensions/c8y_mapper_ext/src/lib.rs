@@ -1,5 +1,6 @@
 pub mod actor;
 pub mod alarm_converter;
+mod child_device_manifest;
 pub mod compatibility_adapter;
 pub mod config;
 pub mod converter;
@@ -8,6 +9,7 @@ pub mod error;
 mod fragments;
 mod inventory;
 pub mod json;
+mod measurement_templates;
 mod operations;
 mod serializer;
 pub mod service_monitor;
@@ -0,0 +1,274 @@
+//! Translation of thin-edge measurements into custom SmartREST 2.0 templates
+//!
+//! By default, measurements are sent to Cumulocity as JSON over MQTT.
+//! A measurement type can instead be mapped to a custom SmartREST template,
+//! by declaring the template in `/etc/tedge/c8y/measurement-templates.toml`:
+//!
+//! ```toml
+//! [[templates]]
+//! type = "environment"
+//! template_id = "tedge-measurements"
+//! message_id = "101"
+//! fields = ["time", "temperature", "location.altitude"]
+//! ```
+//!
+//! A measurement published on `te/device/main///m/environment` is then sent on `c8y/s/uc/tedge-measurements`,
+//! with the message id followed by the values of the given thin-edge JSON fields, in that order:
+//! `101,2024-01-01T00:00:00Z,23.5,512`. A field missing from the measurement is left empty.
+//!
+//! As for the default JSON translation, the `time` is sent as an ISO-8601 timestamp,
+//! a unix timestamp being converted and a missing time being replaced by the current time.
+//!
+//! Only measurements of the main device are translated using templates.
+//! Measurements with no matching template fall back to the default JSON translation.
+use c8y_api::smartrest::csv::fields_to_csv_string;
+use serde::de;
+use serde::Deserialize;
+use serde_json::Value;
+use std::path::Path;
+use std::path::PathBuf;
+use tedge_config::TopicPrefix;
+use tedge_mqtt_ext::Topic;
+use tedge_utils::timestamp::IsoOrUnix;
+use time::format_description::well_known::Rfc3339;
+use time::OffsetDateTime;
+
+pub const MEASUREMENT_TEMPLATES_FILE: &str = "c8y/measurement-templates.toml";
+
+#[derive(Debug, thiserror::Error)]
+pub enum MeasurementTemplatesError {
+    #[error(transparent)]
+    FromIo(#[from] std::io::Error),
+
+    #[error("Error while parsing measurement templates file: '{0}': {1}.")]
+    TomlError(PathBuf, #[source] toml::de::Error),
+}
+
+#[derive(Debug, Default, Deserialize, PartialEq, Eq)]
+pub struct MeasurementTemplates {
+    #[serde(default)]
+    templates: Vec<MeasurementTemplate>,
+}
+
+#[derive(Debug, Deserialize, PartialEq, Eq)]
+pub struct MeasurementTemplate {
+    /// The thin-edge measurement type this template applies to
+    #[serde(rename = "type")]
+    pub measurement_type: String,
+
+    /// The id of the SmartREST template collection
+    pub template_id: String,
+
+    /// The id of the message, in the template collection
+    pub message_id: String,
+
+    /// The thin-edge JSON paths of the template columns, in order
+    pub fields: Vec<String>,
+}
+
+impl MeasurementTemplates {
+    /// Load the templates from the given file, if any
+    pub fn load(path: impl AsRef<Path>) -> Result<Self, MeasurementTemplatesError> {
+        let path = path.as_ref();
+        match std::fs::read_to_string(path) {
+            Ok(content) => toml::from_str(&content)
+                .map_err(|e| MeasurementTemplatesError::TomlError(path.to_path_buf(), e)),
+            Err(err) if err.kind() == std::io::ErrorKind::NotFound => Ok(Self::default()),
+            Err(err) => Err(err.into()),
+        }
+    }
+
+    /// Return the template to be used for the given measurement type, if any
+    pub fn get(&self, measurement_type: &str) -> Option<&MeasurementTemplate> {
+        self.templates
+            .iter()
+            .find(|template| template.measurement_type == measurement_type)
+    }
+}
+
+impl MeasurementTemplate {
+    /// The topic on which the SmartREST messages of this template have to be published
+    pub fn topic(&self, prefix: &TopicPrefix) -> Topic {
+        Topic::new_unchecked(&format!("{prefix}/s/uc/{}", self.template_id))
+    }
+
+    /// Render a thin-edge JSON measurement as a SmartREST message of this template
+    ///
+    /// The `default_timestamp` is used when the measurement has no `time`.
+    pub fn render(
+        &self,
+        measurement: &Value,
+        default_timestamp: OffsetDateTime,
+    ) -> Result<String, serde_json::Error> {
+        let timestamp = match measurement.get("time") {
+            Some(time) => IsoOrUnix::try_from(time)?.into(),
+            None => default_timestamp,
+        };
+        let time = timestamp
+            .format(&Rfc3339)
+            .map_err(|e| de::Error::custom(e.to_string()))?;
+
+        let values: Vec<String> = self
+            .fields
+            .iter()
+            .map(|field| match field.as_str() {
+                "time" => time.clone(),
+                _ => field_value(measurement, field),
+            })
+            .collect();
+
+        let mut record = vec![self.message_id.as_str()];
+        record.extend(values.iter().map(String::as_str));
+        Ok(fields_to_csv_string(&record))
+    }
+}
+
+/// Extract the value of a dot-separated path, leaving it empty if missing or not a scalar
+fn field_value(measurement: &Value, path: &str) -> String {
+    let value = path
+        .split('.')
+        .try_fold(measurement, |value, key| value.get(key));
+
+    match value {
+        Some(Value::String(value)) => value.clone(),
+        Some(Value::Number(value)) => value.to_string(),
+        Some(Value::Bool(value)) => value.to_string(),
+        _ => String::new(),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+    use tedge_test_utils::fs::TempTedgeDir;
+    use time::macros::datetime;
+
+    #[test]
+    fn load_templates_from_file() {
+        let ttd = TempTedgeDir::new();
+        ttd.dir("c8y")
+            .file("measurement-templates.toml")
+            .with_raw_content(
+                r#"
+            [[templates]]
+            type = "environment"
+            template_id = "tedge-measurements"
+            message_id = "101"
+            fields = ["time", "temperature"]
+            "#,
+            );
+
+        let templates =
+            MeasurementTemplates::load(ttd.path().join(MEASUREMENT_TEMPLATES_FILE)).unwrap();
+
+        assert_eq!(
+            templates.get("environment"),
+            Some(&MeasurementTemplate {
+                measurement_type: "environment".into(),
+                template_id: "tedge-measurements".into(),
+                message_id: "101".into(),
+                fields: vec!["time".into(), "temperature".into()],
+            })
+        );
+        assert_eq!(templates.get("other"), None);
+    }
+
+    #[test]
+    fn missing_templates_file_means_no_templates() {
+        let ttd = TempTedgeDir::new();
+
+        let templates =
+            MeasurementTemplates::load(ttd.path().join(MEASUREMENT_TEMPLATES_FILE)).unwrap();
+
+        assert_eq!(templates, MeasurementTemplates::default());
+    }
+
+    #[test]
+    fn render_measurement_fields_in_order() {
+        let template = MeasurementTemplate {
+            measurement_type: "environment".into(),
+            template_id: "tedge-measurements".into(),
+            message_id: "101".into(),
+            fields: vec![
+                "time".into(),
+                "location.altitude".into(),
+                "temperature".into(),
+                "humidity".into(),
+                "location".into(),
+            ],
+        };
+        let measurement = json!({
+            "time": "2024-01-01T00:00:00Z",
+            "temperature": 23.5,
+            "location": { "altitude": 512, "latitude": 50.1 }
+        });
+
+        assert_eq!(
+            template
+                .render(&measurement, OffsetDateTime::UNIX_EPOCH)
+                .unwrap(),
+            "101,2024-01-01T00:00:00Z,512,23.5,,"
+        );
+        assert_eq!(
+            template.topic(&"c8y".try_into().unwrap()).name,
+            "c8y/s/uc/tedge-measurements"
+        );
+    }
+
+    #[test]
+    fn render_unix_timestamps_as_iso_8601() {
+        let template = time_and_temperature_template();
+        let measurement = json!({ "time": 1702999999, "temperature": 23.5 });
+
+        assert_eq!(
+            template
+                .render(&measurement, OffsetDateTime::UNIX_EPOCH)
+                .unwrap(),
+            "101,2023-12-19T15:33:19Z,23.5"
+        );
+    }
+
+    #[test]
+    fn render_missing_time_as_the_default_timestamp() {
+        let template = time_and_temperature_template();
+        let measurement = json!({ "temperature": 23.5 });
+
+        assert_eq!(
+            template
+                .render(&measurement, datetime!(2024-01-01 12:00:00 UTC))
+                .unwrap(),
+            "101,2024-01-01T12:00:00Z,23.5"
+        );
+    }
+
+    #[test]
+    fn reject_invalid_time() {
+        let template = time_and_temperature_template();
+        let measurement = json!({ "time": true, "temperature": 23.5 });
+
+        assert!(template
+            .render(&measurement, OffsetDateTime::UNIX_EPOCH)
+            .is_err());
+    }
+
+    #[test]
+    fn reject_time_that_cannot_be_formatted_as_rfc3339() {
+        let template = time_and_temperature_template();
+        // A negative year
+        let measurement = json!({ "time": -100000000000i64, "temperature": 23.5 });
+
+        assert!(template
+            .render(&measurement, OffsetDateTime::UNIX_EPOCH)
+            .is_err());
+    }
+
+    fn time_and_temperature_template() -> MeasurementTemplate {
+        MeasurementTemplate {
+            measurement_type: "environment".into(),
+            template_id: "tedge-measurements".into(),
+            message_id: "101".into(),
+            fields: vec!["time".into(), "temperature".into()],
+        }
+    }
+}
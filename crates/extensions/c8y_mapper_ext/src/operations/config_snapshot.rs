@@ -28,6 +28,7 @@ use tedge_downloader_ext::DownloadResult;
 use tedge_mqtt_ext::MqttMessage;
 use tedge_mqtt_ext::QoS;
 use tedge_mqtt_ext::TopicFilter;
+use tedge_utils::gzip;
 use tracing::log::warn;
 
 pub fn topic_filter(mqtt_schema: &MqttSchema) -> TopicFilter {
@@ -224,8 +225,22 @@ impl CumulocityConverter {
         let file_path = Utf8PathBuf::try_from(download.file_path).map_err(|e| e.into_io_error())?;
         let event_type = response.config_type.clone();
 
+        let (file_path, mime_type) = if self.config.compress_config_snapshot {
+            let compressed_file_path = Utf8PathBuf::from(format!("{file_path}.gz"));
+            // Compressing a large snapshot must not block the processing of other messages
+            let (src, dest) = (file_path.clone(), compressed_file_path.clone());
+            tokio::task::spawn_blocking(move || gzip::compress_file(src, dest))
+                .await
+                .map_err(std::io::Error::other)??;
+            (compressed_file_path, "application/gzip".parse().ok())
+        } else {
+            (file_path, None)
+        };
+
         let binary_upload_event_url = self
-            .upload_file(&topic_id, &file_path, None, None, &cmd_id, event_type, None)
+            .upload_file(
+                &topic_id, &file_path, None, mime_type, &cmd_id, event_type, None,
+            )
             .await?;
 
         self.pending_upload_operations.insert(
@@ -283,7 +298,10 @@ mod tests {
     use tedge_mqtt_ext::MqttMessage;
     use tedge_mqtt_ext::Topic;
     use tedge_test_utils::fs::TempTedgeDir;
+    use tedge_uploader_ext::ContentType;
+    use tedge_uploader_ext::FormData;
     use tedge_uploader_ext::UploadResponse;
+    use tedge_utils::gzip;
 
     const TEST_TIMEOUT_MS: Duration = Duration::from_millis(3000);
 
@@ -628,6 +646,68 @@ mod tests {
             .await;
     }
 
+    #[tokio::test]
+    async fn handle_config_snapshot_successful_cmd_with_compression() {
+        let ttd = TempTedgeDir::new();
+        let config = C8yMapperConfig {
+            compress_config_snapshot: true,
+            ..test_mapper_config(&ttd)
+        };
+        let test_handle = spawn_c8y_mapper_actor_with_config(&ttd, config, true).await;
+        spawn_dummy_c8y_http_proxy(test_handle.c8y_http_box);
+
+        let mut mqtt = test_handle.mqtt_box.with_timeout(TEST_TIMEOUT_MS);
+        let mut ul = test_handle.ul_box.with_timeout(TEST_TIMEOUT_MS);
+        let mut dl = test_handle.dl_box.with_timeout(TEST_TIMEOUT_MS);
+        skip_init_messages(&mut mqtt).await;
+
+        // Simulate config_snapshot command with "successful" state
+        mqtt.send(MqttMessage::new(
+            &Topic::new_unchecked("te/device/main///cmd/config_snapshot/c8y-mapper-1234"),
+            json!({
+            "status": "successful",
+            "tedgeUrl": "http://localhost:8888/tedge/file-transfer/test-device/config_snapshot/path:type:A-c8y-mapper-1234",
+            "type": "path/type/A",
+        })
+                .to_string(),
+        ))
+            .await
+            .expect("Send failed");
+
+        // Simulate the downloader fetching the snapshot from the file-transfer service
+        let download_request = dl.recv().await.expect("timeout");
+        std::fs::write(&download_request.1.file_path, "some config content").unwrap();
+        dl.send((
+            download_request.0,
+            Ok(DownloadResponse {
+                url: download_request.1.url,
+                file_path: download_request.1.file_path,
+            }),
+        ))
+        .await
+        .unwrap();
+
+        // The uploader is given the compressed snapshot
+        let request = ul.recv().await.expect("timeout");
+        assert_eq!(request.0, "c8y-mapper-1234"); // Command ID
+        assert!(request.1.file_path.as_str().ends_with(".gz"));
+        assert!(gzip::is_gzipped(&request.1.file_path).unwrap());
+        assert_eq!(
+            request.1.content_type,
+            ContentType::FormData(
+                FormData::new("test-device_path:type:A-c8y-mapper-1234.gz".into())
+                    .set_mime("application/gzip".parse().unwrap())
+            )
+        );
+
+        let decompressed = ttd.utf8_path().join("decompressed");
+        gzip::decompress_file(&request.1.file_path, &decompressed).unwrap();
+        assert_eq!(
+            std::fs::read_to_string(decompressed).unwrap(),
+            "some config content"
+        );
+    }
+
     #[tokio::test]
     async fn auto_log_upload_successful_operation() {
         let ttd = TempTedgeDir::new();
@@ -1,6 +1,44 @@
+//! Translation of thin-edge service health status into Cumulocity
+//!
+//! By default, the status of a service is reflected by the status, i.e. the availability,
+//! of the service managed object in Cumulocity.
+//! This can be changed per service name pattern, in `/etc/tedge/c8y/service-monitoring.toml`:
+//!
+//! ```toml
+//! # Effects for the services matching no rule
+//! default = ["availability"]
+//!
+//! [[services]]
+//! name = "tedge-*"
+//! effects = ["availability", "alarm"]
+//!
+//! [[services]]
+//! name = "collectd"
+//! effects = []
+//! ```
+//!
+//! The first rule whose glob pattern matches the service name is applied.
+//! The service name is the one used in the service topic identifier, e.g. `tedge-agent` for `device/main/service/tedge-agent`,
+//! and not the display name of the service that can be changed by a registration message.
+//! The display name is only used for services registered with a custom topic scheme.
+//! With the `alarm` effect, a `service_down` alarm is raised on the service when it is down,
+//! and cleared when it is up again. Only the alarms raised by the mapper since it started are cleared.
+//! The service managed object is created on registration, when not created by the `availability` effect.
+//!
+//! The file is reloaded by the mapper on change.
 use c8y_api::smartrest;
+use c8y_api::smartrest::csv::fields_to_csv_string;
+use c8y_api::smartrest::topic::publish_topic_from_ancestors;
+use glob::Pattern;
+use glob::PatternError;
+use serde::Deserialize;
+use std::collections::HashSet;
+use std::path::Path;
+use std::path::PathBuf;
+use tedge_api::entity_store::EntityExternalId;
 use tedge_api::entity_store::EntityMetadata;
 use tedge_api::entity_store::EntityType;
+use tedge_api::health::Status;
 use tedge_api::mqtt_topics::MqttSchema;
 use tedge_api::HealthStatus;
 use tedge_config::TopicPrefix;
@@ -8,6 +46,120 @@ use tedge_mqtt_ext::MqttMessage;
 use tedge_mqtt_ext::Topic;
 use tracing::error;
 
+pub const SERVICE_MONITORING_FILE: &str = "c8y/service-monitoring.toml";
+
+/// The type of the alarm raised when a service is down
+const SERVICE_DOWN_ALARM_TYPE: &str = "service_down";
+
+#[derive(Debug, thiserror::Error)]
+pub enum ServiceMonitoringError {
+    #[error(transparent)]
+    FromIo(#[from] std::io::Error),
+
+    #[error("Error while parsing service monitoring file: '{0}': {1}.")]
+    TomlError(PathBuf, #[source] toml::de::Error),
+
+    #[error("Invalid service name pattern: '{0}': {1}.")]
+    InvalidPattern(String, #[source] PatternError),
+}
+
+/// The effect of a service status change in Cumulocity
+#[derive(Debug, Clone, Copy, Deserialize, PartialEq, Eq)]
+#[serde(rename_all = "lowercase")]
+pub enum ServiceStatusEffect {
+    /// Update the status of the service managed object
+    Availability,
+
+    /// Raise an alarm when the service is down, and clear it when up
+    Alarm,
+}
+
+/// The effects of service status changes, per service name pattern
+#[derive(Debug, PartialEq)]
+pub struct ServiceMonitoring {
+    default: Vec<ServiceStatusEffect>,
+    services: Vec<(Pattern, Vec<ServiceStatusEffect>)>,
+}
+
+#[derive(Debug, Deserialize)]
+struct ServiceMonitoringDto {
+    #[serde(default = "default_effects")]
+    default: Vec<ServiceStatusEffect>,
+
+    #[serde(default)]
+    services: Vec<ServiceRuleDto>,
+}
+
+#[derive(Debug, Deserialize)]
+struct ServiceRuleDto {
+    name: String,
+    effects: Vec<ServiceStatusEffect>,
+}
+
+fn default_effects() -> Vec<ServiceStatusEffect> {
+    vec![ServiceStatusEffect::Availability]
+}
+
+impl Default for ServiceMonitoring {
+    fn default() -> Self {
+        ServiceMonitoring {
+            default: default_effects(),
+            services: vec![],
+        }
+    }
+}
+
+impl ServiceMonitoring {
+    /// Load the service monitoring rules from the given file, if any
+    pub fn load(path: impl AsRef<Path>) -> Result<Self, ServiceMonitoringError> {
+        let path = path.as_ref();
+        match std::fs::read_to_string(path) {
+            Ok(content) => {
+                let dto: ServiceMonitoringDto = toml::from_str(&content)
+                    .map_err(|e| ServiceMonitoringError::TomlError(path.to_path_buf(), e))?;
+                dto.try_into()
+            }
+            Err(err) if err.kind() == std::io::ErrorKind::NotFound => Ok(Self::default()),
+            Err(err) => Err(err.into()),
+        }
+    }
+
+    /// Return the effects of a status change of the given service
+    pub fn effects(&self, service_name: &str) -> &[ServiceStatusEffect] {
+        self.services
+            .iter()
+            .find(|(pattern, _)| pattern.matches(service_name))
+            .map_or(&self.default, |(_, effects)| effects)
+    }
+
+    /// Return true if the service managed object has to be created for the alarms of the given service
+    ///
+    /// This is the case when the `alarm` effect applies without the `availability` effect,
+    /// as the latter creates the service managed object on which the alarms are raised.
+    pub fn requires_service_creation(&self, service_name: &str) -> bool {
+        let effects = self.effects(service_name);
+        effects.contains(&ServiceStatusEffect::Alarm)
+            && !effects.contains(&ServiceStatusEffect::Availability)
+    }
+}
+
+impl TryFrom<ServiceMonitoringDto> for ServiceMonitoring {
+    type Error = ServiceMonitoringError;
+
+    fn try_from(dto: ServiceMonitoringDto) -> Result<Self, Self::Error> {
+        let mut services = vec![];
+        for rule in dto.services {
+            let pattern = Pattern::new(&rule.name)
+                .map_err(|e| ServiceMonitoringError::InvalidPattern(rule.name.clone(), e))?;
+            services.push((pattern, rule.effects));
+        }
+        Ok(ServiceMonitoring {
+            default: dto.default,
+            services,
+        })
+    }
+}
+
 pub fn is_c8y_bridge_established(
     message: &MqttMessage,
     mqtt_schema: &MqttSchema,
@@ -26,6 +178,8 @@ pub fn convert_health_status_message(
     ancestors_external_ids: &[String],
     message: &MqttMessage,
     prefix: &TopicPrefix,
+    service_monitoring: &ServiceMonitoring,
+    raised_alarms: &mut HashSet<EntityExternalId>,
 ) -> Vec<MqttMessage> {
     // TODO: introduce type to remove entity type guards
     if entity.r#type != EntityType::Service {
@@ -51,19 +205,54 @@ pub fn convert_health_status_message(
         .and_then(|v| v.as_str())
         .expect("display type should be inserted for every service in the converter");
 
-    let Ok(status_message) = smartrest::inventory::service_creation_message(
-        entity.external_id.as_ref(),
-        display_name,
-        display_type,
-        &status.to_string(),
-        ancestors_external_ids,
-        prefix,
-    ) else {
-        error!("Can't create 102 for service status update");
-        return vec![];
-    };
+    // The rules apply to the service name of the topic id, which is not changed by a registration message
+    let service_name = entity
+        .topic_id
+        .default_service_name()
+        .unwrap_or(display_name);
 
-    vec![status_message]
+    let mut messages = vec![];
+    for effect in service_monitoring.effects(service_name) {
+        match effect {
+            ServiceStatusEffect::Availability => {
+                let Ok(status_message) = smartrest::inventory::service_creation_message(
+                    entity.external_id.as_ref(),
+                    display_name,
+                    display_type,
+                    &status.to_string(),
+                    ancestors_external_ids,
+                    prefix,
+                ) else {
+                    error!("Can't create 102 for service status update");
+                    continue;
+                };
+                messages.push(status_message);
+            }
+            ServiceStatusEffect::Alarm => {
+                let payload = match status {
+                    Status::Down => {
+                        raised_alarms.insert(entity.external_id.clone());
+                        fields_to_csv_string(&[
+                            "302",
+                            SERVICE_DOWN_ALARM_TYPE,
+                            &format!("Service {display_name} is down"),
+                        ])
+                    }
+                    // Only the alarms raised by the mapper are cleared
+                    Status::Up if raised_alarms.remove(&entity.external_id) => {
+                        fields_to_csv_string(&["306", SERVICE_DOWN_ALARM_TYPE])
+                    }
+                    Status::Up | Status::Other(_) => continue,
+                };
+                let mut service_and_ancestors = vec![entity.external_id.as_ref().to_string()];
+                service_and_ancestors.extend_from_slice(ancestors_external_ids);
+                let topic = publish_topic_from_ancestors(&service_and_ancestors, prefix);
+                messages.push(MqttMessage::new(&topic, payload));
+            }
+        }
+    }
+
+    messages
 }
 
 #[cfg(test)]
@@ -73,6 +262,7 @@ mod tests {
     use tedge_api::entity_store::EntityStore;
     use tedge_api::mqtt_topics::MqttSchema;
     use tedge_mqtt_ext::Topic;
+    use tedge_test_utils::fs::TempTedgeDir;
     use test_case::test_case;
 
     #[test_case(
@@ -212,10 +402,298 @@ mod tests {
             &ancestors_external_ids,
             &health_message,
             &"c8y".try_into().unwrap(),
+            &ServiceMonitoring::default(),
+            &mut HashSet::new(),
         );
         assert_eq!(msg[0], expected_message);
     }
 
+    #[test]
+    fn load_service_monitoring_rules() {
+        let ttd = TempTedgeDir::new();
+        ttd.dir("c8y")
+            .file("service-monitoring.toml")
+            .with_raw_content(
+                r#"
+            default = ["alarm"]
+
+            [[services]]
+            name = "tedge-*"
+            effects = ["availability", "alarm"]
+
+            [[services]]
+            name = "collectd"
+            effects = []
+            "#,
+            );
+
+        let rules = ServiceMonitoring::load(ttd.path().join(SERVICE_MONITORING_FILE)).unwrap();
+
+        assert_eq!(
+            rules.effects("tedge-agent"),
+            &[
+                ServiceStatusEffect::Availability,
+                ServiceStatusEffect::Alarm
+            ]
+        );
+        assert!(rules.effects("collectd").is_empty());
+        assert_eq!(rules.effects("mosquitto"), &[ServiceStatusEffect::Alarm]);
+    }
+
+    #[test]
+    fn services_are_created_for_alarms_without_availability() {
+        let rules = ServiceMonitoring {
+            default: vec![ServiceStatusEffect::Availability],
+            services: vec![
+                (
+                    Pattern::new("tedge-*").unwrap(),
+                    vec![
+                        ServiceStatusEffect::Availability,
+                        ServiceStatusEffect::Alarm,
+                    ],
+                ),
+                (
+                    Pattern::new("collectd").unwrap(),
+                    vec![ServiceStatusEffect::Alarm],
+                ),
+                (Pattern::new("mosquitto").unwrap(), vec![]),
+            ],
+        };
+
+        assert!(rules.requires_service_creation("collectd"));
+        assert!(!rules.requires_service_creation("tedge-agent"));
+        assert!(!rules.requires_service_creation("mosquitto"));
+        assert!(!rules.requires_service_creation("my-service"));
+    }
+
+    #[test]
+    fn missing_service_monitoring_file_means_default_rules() {
+        let ttd = TempTedgeDir::new();
+
+        let rules = ServiceMonitoring::load(ttd.path().join(SERVICE_MONITORING_FILE)).unwrap();
+
+        assert_eq!(rules, ServiceMonitoring::default());
+        assert_eq!(
+            rules.effects("tedge-agent"),
+            &[ServiceStatusEffect::Availability]
+        );
+    }
+
+    #[test]
+    fn invalid_service_name_patterns_are_rejected() {
+        let ttd = TempTedgeDir::new();
+        ttd.dir("c8y")
+            .file("service-monitoring.toml")
+            .with_raw_content(
+                r#"
+            [[services]]
+            name = "tedge-[agent"
+            effects = ["alarm"]
+            "#,
+            );
+
+        let result = ServiceMonitoring::load(ttd.path().join(SERVICE_MONITORING_FILE));
+
+        assert!(matches!(
+            result,
+            Err(ServiceMonitoringError::InvalidPattern(..))
+        ));
+    }
+
+    #[test_case("up", &[
+        "102,test_device:device:child:service:tedge-agent,service,tedge-agent,up",
+    ]; "up with no raised alarm")]
+    #[test_case("down", &[
+        "102,test_device:device:child:service:tedge-agent,service,tedge-agent,down",
+        "302,service_down,Service tedge-agent is down",
+    ]; "down raises an alarm")]
+    #[test_case("unknown", &[
+        "102,test_device:device:child:service:tedge-agent,service,tedge-agent,unknown",
+    ]; "unknown status has no alarm effect")]
+    fn service_status_changes_have_the_configured_effects(status: &str, expected: &[&str]) {
+        let rules = ServiceMonitoring {
+            default: vec![],
+            services: vec![(
+                Pattern::new("tedge-*").unwrap(),
+                vec![
+                    ServiceStatusEffect::Availability,
+                    ServiceStatusEffect::Alarm,
+                ],
+            )],
+        };
+
+        let messages = convert_service_status(
+            "te/device/child/service/tedge-agent/status/health",
+            &format!(r#"{{"status":"{status}"}}"#),
+            None,
+            &rules,
+            &mut HashSet::new(),
+        );
+
+        let expected: Vec<_> = expected
+            .iter()
+            .map(|payload| {
+                let topic = if payload.starts_with("102") {
+                    "c8y/s/us/test_device:device:child"
+                } else {
+                    "c8y/s/us/test_device:device:child/test_device:device:child:service:tedge-agent"
+                };
+                MqttMessage::new(&Topic::new_unchecked(topic), *payload)
+            })
+            .collect();
+        assert_eq!(messages, expected);
+    }
+
+    #[test]
+    fn only_the_alarms_raised_by_the_mapper_are_cleared() {
+        let rules = ServiceMonitoring {
+            default: vec![ServiceStatusEffect::Alarm],
+            services: vec![],
+        };
+        let mut raised_alarms = HashSet::new();
+        let mut convert = |status: &str| {
+            convert_service_status(
+                "te/device/main/service/tedge-agent/status/health",
+                &format!(r#"{{"status":"{status}"}}"#),
+                None,
+                &rules,
+                &mut raised_alarms,
+            )
+            .into_iter()
+            .map(|message| message.payload_str().unwrap().to_string())
+            .collect::<Vec<_>>()
+        };
+
+        assert!(convert("up").is_empty());
+        assert_eq!(
+            convert("down"),
+            vec!["302,service_down,Service tedge-agent is down"]
+        );
+        assert_eq!(convert("up"), vec!["306,service_down"]);
+        assert!(convert("up").is_empty());
+    }
+
+    #[test]
+    fn unmapped_services_get_the_default_effects() {
+        let rules = ServiceMonitoring {
+            default: vec![ServiceStatusEffect::Availability],
+            services: vec![(Pattern::new("collectd").unwrap(), vec![])],
+        };
+
+        let mapped = convert_service_status(
+            "te/device/main/service/collectd/status/health",
+            r#"{"status":"down"}"#,
+            None,
+            &rules,
+            &mut HashSet::new(),
+        );
+        let unmapped = convert_service_status(
+            "te/device/main/service/tedge-agent/status/health",
+            r#"{"status":"down"}"#,
+            None,
+            &rules,
+            &mut HashSet::new(),
+        );
+
+        assert!(mapped.is_empty());
+        assert_eq!(
+            unmapped,
+            vec![MqttMessage::new(
+                &Topic::new_unchecked("c8y/s/us"),
+                "102,test_device:device:main:service:tedge-agent,service,tedge-agent,down"
+            )]
+        );
+    }
+
+    #[test]
+    fn services_are_matched_by_their_topic_id_and_not_their_display_name() {
+        let rules = ServiceMonitoring {
+            default: vec![ServiceStatusEffect::Availability],
+            services: vec![(Pattern::new("collectd").unwrap(), vec![])],
+        };
+
+        let renamed = convert_service_status(
+            "te/device/main/service/collectd/status/health",
+            r#"{"status":"down"}"#,
+            Some("Metrics collector"),
+            &rules,
+            &mut HashSet::new(),
+        );
+        let named_after_a_rule = convert_service_status(
+            "te/device/main/service/tedge-agent/status/health",
+            r#"{"status":"down"}"#,
+            Some("collectd"),
+            &rules,
+            &mut HashSet::new(),
+        );
+
+        assert!(renamed.is_empty());
+        assert_eq!(
+            named_after_a_rule,
+            vec![MqttMessage::new(
+                &Topic::new_unchecked("c8y/s/us"),
+                "102,test_device:device:main:service:tedge-agent,service,collectd,down"
+            )]
+        );
+    }
+
+    fn convert_service_status(
+        health_topic: &str,
+        health_payload: &str,
+        display_name: Option<&str>,
+        rules: &ServiceMonitoring,
+        raised_alarms: &mut HashSet<EntityExternalId>,
+    ) -> Vec<MqttMessage> {
+        let topic = Topic::new_unchecked(health_topic);
+        let mqtt_schema = MqttSchema::new();
+        let (entity_topic_id, _) = mqtt_schema.entity_channel_of(&topic).unwrap();
+        let health_message = MqttMessage::new(&topic, health_payload);
+
+        let temp_dir = tempfile::tempdir().unwrap();
+        let main_device_registration =
+            EntityRegistrationMessage::main_device("test_device".to_string());
+        let mut entity_store = EntityStore::with_main_device_and_default_service_type(
+            MqttSchema::default(),
+            main_device_registration,
+            "service".into(),
+            crate::converter::CumulocityConverter::map_to_c8y_external_id,
+            crate::converter::CumulocityConverter::validate_external_id,
+            5,
+            &temp_dir,
+            true,
+        )
+        .unwrap();
+        entity_store.auto_register_entity(&entity_topic_id).unwrap();
+        if let Some(name) = display_name {
+            let mut other = serde_json::Map::new();
+            other.insert("name".to_string(), name.into());
+            entity_store
+                .update(EntityRegistrationMessage {
+                    topic_id: entity_topic_id.clone(),
+                    external_id: None,
+                    r#type: EntityType::Service,
+                    parent: None,
+                    other,
+                })
+                .unwrap();
+        }
+
+        let entity = entity_store.get(&entity_topic_id).unwrap();
+        let ancestors_external_ids = entity_store
+            .ancestors_external_ids(&entity_topic_id)
+            .unwrap();
+
+        convert_health_status_message(
+            &mqtt_schema,
+            entity,
+            &ancestors_external_ids,
+            &health_message,
+            &"c8y".try_into().unwrap(),
+            rules,
+            raised_alarms,
+        )
+    }
+
     const C8Y_BRIDGE_HEALTH_TOPIC: &str =
         "te/device/main/service/mosquitto-c8y-bridge/status/health";
 
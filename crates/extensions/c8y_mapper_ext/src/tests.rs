@@ -159,6 +159,67 @@ async fn child_device_registration_mapping() {
     .await;
 }
 
+#[tokio::test]
+async fn manifest_child_devices_are_registered_after_the_sync_phase() {
+    let cfg_dir = TempTedgeDir::new();
+    cfg_dir
+        .dir("c8y")
+        .file("child-devices.json")
+        .with_raw_content(
+            r#"[
+            { "id": "child01", "type": "SmartHomeHub", "name": "Living room hub" },
+            { "id": "child02" }
+        ]"#,
+        );
+    let (mqtt, _http, _fs, mut timer, _ul, _dl) = spawn_c8y_mapper_actor(&cfg_dir, true).await;
+
+    let mut mqtt = mqtt.with_timeout(TEST_TIMEOUT_MS);
+    skip_init_messages(&mut mqtt).await;
+
+    // A retained registration with a custom external id is received during the sync phase
+    mqtt.send(
+        MqttMessage::new(
+            &Topic::new_unchecked("te/device/child01//"),
+            r#"{ "@type": "child-device", "@id": "custom-child01", "name": "Kitchen hub" }"#,
+        )
+        .with_retain(),
+    )
+    .await
+    .unwrap();
+
+    assert_received_contains_str(
+        &mut mqtt,
+        [(
+            "c8y/s/us",
+            "101,custom-child01,Kitchen hub,thin-edge.io-child",
+        )],
+    )
+    .await;
+
+    // Complete sync phase so that the manifest is applied
+    trigger_timeout(&mut timer).await;
+
+    // Only the child device not registered yet is registered from the manifest
+    assert_received_contains_str(
+        &mut mqtt,
+        [
+            (
+                "c8y/s/us",
+                "101,test-device:device:child02,child02,thin-edge.io-child",
+            ),
+            (
+                "te/device/child02//",
+                r#"{"@id":"test-device:device:child02","@type":"child-device","name":"child02"}"#,
+            ),
+        ],
+    )
+    .await;
+    assert!(
+        mqtt.recv().await.is_none(),
+        "The retained registration of child01 must not be overwritten by the manifest"
+    );
+}
+
 #[tokio::test]
 async fn custom_topic_scheme_registration_mapping() {
     let cfg_dir = TempTedgeDir::new();
@@ -2622,6 +2683,7 @@ pub(crate) fn test_mapper_config(tmp_dir: &TempTedgeDir) -> C8yMapperConfig {
         device_type,
         config.service.clone(),
         c8y_host,
+        vec![],
         tedge_http_host,
         topics,
         Capabilities::default(),
@@ -2636,6 +2698,7 @@ pub(crate) fn test_mapper_config(tmp_dir: &TempTedgeDir) -> C8yMapperConfig {
         SoftwareManagementApiFlag::Advanced,
         true,
         AutoLogUpload::Never,
+        false,
     )
 }
 
@@ -155,9 +155,10 @@ impl CollectdPayload {
         let metric_values = msg
             .into_iter()
             .skip(1)
-            .map(|m| {
-                m.parse::<f64>()
-                    .map_err(|_err| CollectdPayloadError::InvalidMeasurementValue(m.to_string()))
+            .map(|m| match m.parse::<f64>() {
+                // NaN and infinite values cannot be represented in thin-edge JSON
+                Ok(value) if value.is_finite() => Ok(value),
+                _ => Err(CollectdPayloadError::InvalidMeasurementValue(m.to_string())),
             })
             .collect::<Result<Vec<_>, _>>()?;
 
@@ -335,6 +336,39 @@ mod tests {
         );
     }
 
+    #[test]
+    fn invalid_collectd_metric_nan_value() {
+        let payload = "123456789:nan";
+        let result = CollectdPayload::parse_from(payload);
+
+        assert_matches!(
+            result,
+            Err(CollectdPayloadError::InvalidMeasurementValue(_))
+        );
+    }
+
+    #[test]
+    fn invalid_collectd_metric_infinite_value() {
+        let payload = "123456789:-inf";
+        let result = CollectdPayload::parse_from(payload);
+
+        assert_matches!(
+            result,
+            Err(CollectdPayloadError::InvalidMeasurementValue(_))
+        );
+    }
+
+    #[test]
+    fn invalid_collectd_metric_empty_value() {
+        let payload = "123456789:";
+        let result = CollectdPayload::parse_from(payload);
+
+        assert_matches!(
+            result,
+            Err(CollectdPayloadError::InvalidMeasurementValue(_))
+        );
+    }
+
     #[test]
     fn valid_collectd_multivalue_metric() {
         let payload = "123456789:1234:5678";
@@ -30,6 +30,7 @@ use tedge_mqtt_ext::QoS;
 use tedge_mqtt_ext::Topic;
 use tedge_uploader_ext::UploadRequest;
 use tedge_uploader_ext::UploadResult;
+use tedge_utils::gzip;
 use tedge_write::CopyOptions;
 
 use crate::TedgeWriteStatus;
@@ -436,16 +437,42 @@ impl ConfigManagerActor {
 
         let to = Utf8PathBuf::from(&file_entry.path);
 
+        // A gzipped configuration file is deployed decompressed,
+        // unless the configuration file is expected to be a gzipped archive.
+        if !is_gzip_archive_path(&to) && gzip::is_gzipped(from)? {
+            let decompressed_path = Utf8PathBuf::from(format!("{from}.decompressed"));
+            let result = gzip::decompress_file(from, &decompressed_path)
+                .map_err(ConfigManagementError::from)
+                .and_then(|()| self.write_config_file(&decompressed_path, &to, mode, user, group));
+            if let Err(err) = std::fs::remove_file(&decompressed_path) {
+                error!("Failed to remove the decompressed configuration file {decompressed_path}: {err}");
+            }
+            result?;
+        } else {
+            self.write_config_file(from, &to, mode, user, group)?;
+        }
+
+        Ok(to)
+    }
+
+    fn write_config_file(
+        &self,
+        from: &Utf8Path,
+        to: &Utf8Path,
+        mode: Option<u32>,
+        user: Option<&str>,
+        group: Option<&str>,
+    ) -> Result<(), ConfigManagementError> {
         match self.config.use_tedge_write.clone() {
             TedgeWriteStatus::Disabled => {
                 let src_file = std::fs::File::open(from)?;
-                tedge_utils::fs::atomically_write_file_sync(&to, src_file)?;
+                tedge_utils::fs::atomically_write_file_sync(to, src_file)?;
             }
 
             TedgeWriteStatus::Enabled { sudo } => {
                 let options = CopyOptions {
                     from,
-                    to: to.as_path(),
+                    to,
                     sudo,
                     mode,
                     user,
@@ -455,7 +482,7 @@ impl ConfigManagerActor {
             }
         }
 
-        Ok(to)
+        Ok(())
     }
 
     async fn process_file_watch_events(&mut self, event: FsWatchEvent) -> Result<(), ChannelError> {
@@ -516,6 +543,11 @@ impl ConfigManagerActor {
     }
 }
 
+/// Return true if the file at the given path is expected to be stored gzipped
+fn is_gzip_archive_path(path: &Utf8Path) -> bool {
+    matches!(path.extension(), Some("gz" | "tgz"))
+}
+
 #[derive(Debug, PartialEq, Eq, Clone)]
 pub enum ConfigOperation {
     Snapshot(Topic, ConfigSnapshotCmdPayload),
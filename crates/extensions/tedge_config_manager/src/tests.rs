@@ -22,6 +22,7 @@ use tedge_mqtt_ext::Topic;
 use tedge_mqtt_ext::TopicFilter;
 use tedge_test_utils::fs::TempTedgeDir;
 use tedge_uploader_ext::UploadResponse;
+use tedge_utils::gzip;
 use toml::from_str;
 use toml::Table;
 
@@ -399,6 +400,57 @@ async fn config_manager_download_update() -> Result<(), anyhow::Error> {
     Ok(())
 }
 
+#[tokio::test]
+async fn config_manager_decompresses_gzipped_update() -> Result<(), anyhow::Error> {
+    let tempdir = prepare()?;
+    let (mut mqtt, _fs, mut downloader, _uploader) =
+        spawn_config_manager_actor(tempdir.path()).await;
+
+    let config_topic = Topic::new_unchecked("te/device/main///cmd/config_update/5678");
+
+    // Let's ignore the reload messages sent on start
+    mqtt.skip(2).await;
+
+    let update_request = r#"
+        {
+            "status": "executing",
+            "tedgeUrl": "http://127.0.0.1:3000/tedge/file-transfer/main/config_update/type_four-5678",
+            "remoteUrl": "http://www.remote.url",
+            "type": "type_four"
+        }"#;
+    mqtt.send(MqttMessage::new(&config_topic, update_request).with_retain())
+        .await?;
+
+    // Simulate the download of a gzipped configuration file
+    let (topic, download_request) = downloader.recv().await.unwrap();
+    let uncompressed = tempdir.path().join("uncompressed");
+    std::fs::write(&uncompressed, "Some compressed content")?;
+    gzip::compress_file(&uncompressed, &download_request.file_path)?;
+    let decompressed = format!("{}.decompressed", download_request.file_path.display());
+    let download_response =
+        DownloadResponse::new(&download_request.url, &download_request.file_path);
+    downloader.send((topic, Ok(download_response))).await?;
+
+    assert_eq!(
+            mqtt.recv().await,
+            Some(MqttMessage::new(
+                &config_topic,
+                format!(r#"{{"status":"successful","tedgeUrl":"http://127.0.0.1:3000/tedge/file-transfer/main/config_update/type_four-5678","remoteUrl":"http://www.remote.url","type":"type_four","path":{:?}}}"#, tempdir.path().join("file_d"))
+            ).with_retain())
+        );
+
+    // The configuration file is deployed decompressed
+    assert_eq!(
+        read_to_string(tempdir.path().join("file_d"))?,
+        "Some compressed content"
+    );
+
+    // And the intermediate decompressed file is removed
+    assert!(!Path::new(&decompressed).exists());
+
+    Ok(())
+}
+
 #[tokio::test]
 async fn request_config_snapshot_that_does_not_exist() -> Result<(), anyhow::Error> {
     let tempdir = prepare()?;
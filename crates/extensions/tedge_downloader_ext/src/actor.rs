@@ -4,7 +4,9 @@ use download::DownloadError;
 use download::DownloadInfo;
 use download::Downloader;
 use log::info;
+use reqwest::Certificate;
 use reqwest::Identity;
+use reqwest::Proxy;
 use std::marker::PhantomData;
 use std::path::Path;
 use std::path::PathBuf;
@@ -70,6 +72,8 @@ pub struct DownloaderActor<T> {
     config: ServerConfig,
     key: std::marker::PhantomData<T>,
     identity: Option<Identity>,
+    proxy: Option<Proxy>,
+    root_certificate: Option<Certificate>,
 }
 
 impl<T> Clone for DownloaderActor<T> {
@@ -78,6 +82,8 @@ impl<T> Clone for DownloaderActor<T> {
             config: self.config,
             key: self.key,
             identity: self.identity.clone(),
+            proxy: self.proxy.clone(),
+            root_certificate: self.root_certificate.clone(),
         }
     }
 }
@@ -88,6 +94,21 @@ impl<T: Message + Default> DownloaderActor<T> {
             config: <_>::default(),
             key: PhantomData,
             identity,
+            proxy: None,
+            root_certificate: None,
+        }
+    }
+
+    /// Download the files through the given HTTP proxy, if any
+    pub fn with_proxy(self, proxy: Option<Proxy>) -> Self {
+        Self { proxy, ..self }
+    }
+
+    /// Trust the given CA certificates, if any, in addition to the system ones
+    pub fn with_root_certificate(self, root_certificate: Option<Certificate>) -> Self {
+        Self {
+            root_certificate,
+            ..self
         }
     }
 
@@ -98,8 +119,8 @@ impl<T: Message + Default> DownloaderActor<T> {
     pub fn with_capacity(self, capacity: usize, identity: Option<Identity>) -> Self {
         Self {
             config: self.config.with_capacity(capacity),
-            key: self.key,
             identity,
+            ..self
         }
     }
 }
@@ -122,7 +143,7 @@ impl<T: Message> Server for DownloaderActor<T> {
             DownloadInfo::new(&request.url)
         };
 
-        let downloader = if let Some(permission) = request.permission {
+        let mut downloader = if let Some(permission) = request.permission {
             Downloader::with_permission(
                 request.file_path.clone(),
                 permission,
@@ -131,6 +152,12 @@ impl<T: Message> Server for DownloaderActor<T> {
         } else {
             Downloader::new(request.file_path.clone(), self.identity.clone())
         };
+        if let Some(proxy) = &self.proxy {
+            downloader.set_proxy(proxy.clone());
+        }
+        if let Some(root_certificate) = &self.root_certificate {
+            downloader.set_root_certificate(root_certificate.clone());
+        }
 
         info!(
             "Downloading from url {} to location {}",